@@ -0,0 +1,276 @@
+// Package analytics summarizes the purchases in the history of cards: grouping them by merchant,
+// assigning categories by user-editable rules, and computing monthly totals, top merchants, trends
+// and daily spend. It also reports the discount achieved on the loads, by the card's factors.
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+// A purchase from the history, with its merchant and category
+type Purchase struct {
+	Item gohever.CardHistoryItem
+
+	Date     time.Time
+	Merchant string
+	Category string
+	Amount   float64
+}
+
+// Returns the purchases in the history, categorized by the rules. Loads are left out.
+func Purchases(items []gohever.CardHistoryItem, rules *Rules) ([]Purchase, error) {
+	var purchases []Purchase
+
+	for _, item := range items {
+		if item.ActionType != gohever.ActionPurchase {
+			continue
+		}
+
+		date, err := item.ParseDate(time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.Id, err)
+		}
+
+		merchant := rules.Merchant(item.BusinessName)
+
+		purchases = append(purchases, Purchase{
+			Item:     item,
+			Date:     date,
+			Merchant: merchant,
+			Category: rules.Category(merchant),
+			Amount:   math.Abs(item.Amount),
+		})
+	}
+
+	return purchases, nil
+}
+
+// Returns only the purchases in the given category
+func FilterCategory(purchases []Purchase, category string) []Purchase {
+	var filtered []Purchase
+	for _, purchase := range purchases {
+		if purchase.Category == category {
+			filtered = append(filtered, purchase)
+		}
+	}
+
+	return filtered
+}
+
+// A total of some purchases
+type Total struct {
+	Amount    float64
+	Purchases int
+}
+
+func (total *Total) add(purchase Purchase) {
+	total.Amount += purchase.Amount
+	total.Purchases++
+}
+
+// The purchases of a month, in total and by category
+type MonthlyTotal struct {
+	Month string // YYYY-MM
+
+	Total
+	Categories map[string]Total
+}
+
+// Returns the totals of every month with purchases, oldest first
+func MonthlyTotals(purchases []Purchase) []MonthlyTotal {
+	months := map[string]*MonthlyTotal{}
+
+	for _, purchase := range purchases {
+		month := purchase.Date.Format("2006-01")
+
+		total, ok := months[month]
+		if !ok {
+			total = &MonthlyTotal{Month: month, Categories: map[string]Total{}}
+			months[month] = total
+		}
+
+		total.add(purchase)
+
+		category := total.Categories[purchase.Category]
+		category.add(purchase)
+		total.Categories[purchase.Category] = category
+	}
+
+	totals := make([]MonthlyTotal, 0, len(months))
+	for _, total := range months {
+		totals = append(totals, *total)
+	}
+
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Month < totals[j].Month
+	})
+
+	return totals
+}
+
+// The purchases in a merchant
+type MerchantTotal struct {
+	Merchant string
+	Category string
+
+	Total
+}
+
+// Returns the n merchants with the highest spend, or all of them when n is zero
+func TopMerchants(purchases []Purchase, n int) []MerchantTotal {
+	merchants := map[string]*MerchantTotal{}
+
+	for _, purchase := range purchases {
+		total, ok := merchants[purchase.Merchant]
+		if !ok {
+			total = &MerchantTotal{Merchant: purchase.Merchant, Category: purchase.Category}
+			merchants[purchase.Merchant] = total
+		}
+
+		total.add(purchase)
+	}
+
+	totals := make([]MerchantTotal, 0, len(merchants))
+	for _, total := range merchants {
+		totals = append(totals, *total)
+	}
+
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Amount != totals[j].Amount {
+			return totals[i].Amount > totals[j].Amount
+		}
+
+		return totals[i].Merchant < totals[j].Merchant
+	})
+
+	if n > 0 && len(totals) > n {
+		totals = totals[:n]
+	}
+
+	return totals
+}
+
+// The spend in a category during a month, compared to the month before
+type Trend struct {
+	Category string
+	Month    string // YYYY-MM
+
+	Amount   float64
+	Previous float64
+}
+
+// The change from the month before, e.g. 0.1 for a 10% rise. It's not defined when nothing was
+// spent the month before.
+func (trend Trend) Change() (float64, bool) {
+	if trend.Previous == 0 {
+		return 0, false
+	}
+
+	return trend.Amount/trend.Previous - 1, true
+}
+
+// Returns the trends of every category, for every month from the first month with purchases to
+// the last one, so months without purchases in a category show as a drop
+func Trends(purchases []Purchase) []Trend {
+	totals := MonthlyTotals(purchases)
+	if len(totals) == 0 {
+		return nil
+	}
+
+	categories := map[string]bool{}
+	for _, total := range totals {
+		for category := range total.Categories {
+			categories[category] = true
+		}
+	}
+
+	byMonth := map[string]MonthlyTotal{}
+	for _, total := range totals {
+		byMonth[total.Month] = total
+	}
+
+	first, _ := time.Parse("2006-01", totals[0].Month)
+	last, _ := time.Parse("2006-01", totals[len(totals)-1].Month)
+
+	var trends []Trend
+
+	for category := range categories {
+		previous := 0.0
+
+		for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+			amount := byMonth[month.Format("2006-01")].Categories[category].Amount
+
+			trends = append(trends, Trend{
+				Category: category,
+				Month:    month.Format("2006-01"),
+				Amount:   amount,
+				Previous: previous,
+			})
+
+			previous = amount
+		}
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Category != trends[j].Category {
+			return trends[i].Category < trends[j].Category
+		}
+
+		return trends[i].Month < trends[j].Month
+	})
+
+	return trends
+}
+
+// The purchases of a single day
+type DailyTotal struct {
+	Date time.Time
+
+	Total
+}
+
+// Returns the totals of every day with purchases, oldest first. Use it with FilterCategory to get
+// the daily restaurant spend of a food card.
+func DailyTotals(purchases []Purchase) []DailyTotal {
+	days := map[time.Time]*DailyTotal{}
+
+	for _, purchase := range purchases {
+		day := time.Date(purchase.Date.Year(), purchase.Date.Month(), purchase.Date.Day(), 0, 0, 0, 0, time.UTC)
+
+		total, ok := days[day]
+		if !ok {
+			total = &DailyTotal{Date: day}
+			days[day] = total
+		}
+
+		total.add(purchase)
+	}
+
+	totals := make([]DailyTotal, 0, len(days))
+	for _, total := range days {
+		totals = append(totals, *total)
+	}
+
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Date.Before(totals[j].Date)
+	})
+
+	return totals
+}
+
+// Returns the days spending above a daily limit, e.g. an employer's daily meal allowance
+func DaysAbove(totals []DailyTotal, limit float64) []DailyTotal {
+	var above []DailyTotal
+	for _, total := range totals {
+		if total.Amount > limit {
+			above = append(above, total)
+		}
+	}
+
+	return above
+}
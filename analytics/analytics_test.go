@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+)
+
+var testHistory = []gohever.CardHistoryItem{
+	{Id: "1", Date: "01/02/2024", ActionType: gohever.ActionLoad, BusinessName: "טעינת כרטיס", Amount: 1000},
+	{Id: "2", Date: "04/02/2024", ActionType: gohever.ActionPurchase, BusinessName: "שופרסל דיל - רמת גן", Amount: 200},
+	{Id: "3", Date: "05/02/2024", ActionType: gohever.ActionPurchase, BusinessName: "מסעדת הדג", Amount: 45},
+	{Id: "4", Date: "05/02/2024 13:10", ActionType: gohever.ActionPurchase, BusinessName: "ארומה, דיזנגוף", Amount: 20},
+	{Id: "5", Date: "03/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "שופרסל  שלי", Amount: 300},
+	{Id: "6", Date: "04/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "סופר פארם סניף 12", Amount: 80},
+	{Id: "7", Date: "06/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "פיצה האט", Amount: 70},
+	{Id: "8", Date: "07/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "חנות הספרים", Amount: 50},
+}
+
+func testPurchases(t *testing.T) []Purchase {
+	purchases, err := Purchases(testHistory, DefaultRules())
+	assert.NoError(t, err)
+
+	return purchases
+}
+
+func TestPurchases(t *testing.T) {
+	purchases := testPurchases(t)
+
+	var merchants, categories []string
+	for _, purchase := range purchases {
+		merchants = append(merchants, purchase.Merchant)
+		categories = append(categories, purchase.Category)
+	}
+
+	// Loads are left out
+	assert.Equal(t, []string{"שופרסל", "מסעדת הדג", "ארומה", "שופרסל", "סופר-פארם", "פיצה האט", "חנות הספרים"}, merchants)
+	assert.Equal(t, []string{
+		CategorySupermarket, CategoryRestaurants, CategoryRestaurants, CategorySupermarket,
+		CategoryPharmacy, CategoryRestaurants, CategoryOther,
+	}, categories)
+
+	_, err := Purchases([]gohever.CardHistoryItem{{Id: "1", Date: "nope", ActionType: gohever.ActionPurchase}}, DefaultRules())
+	assert.EqualError(t, err, `item 1: unable to parse date "nope"`)
+}
+
+func TestRules(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(fileName, []byte(`{
+  "aliases": {"הדג - נמל": "הדג"},
+  "categories": [{"name": "fish", "merchants": ["הדג"]}]
+}`), 0o600)
+
+	rules, err := LoadRules(fileName)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "הדג", rules.Merchant("הדג - יפו"))
+	assert.Equal(t, "fish", rules.Category(rules.Merchant("מסעדת הדג")))
+	assert.Equal(t, CategoryOther, rules.Category("דגים של משה"))
+
+	_, err = ParseRules(strings.NewReader(`{"categories": [{"merchants": ["a"]}]}`))
+	assert.EqualError(t, err, "unable to parse the rules: category 1 has no name")
+}
+
+func TestMonthlyTotals(t *testing.T) {
+	totals := MonthlyTotals(testPurchases(t))
+
+	assert.Equal(t, []MonthlyTotal{
+		{
+			Month: "2024-02",
+			Total: Total{Amount: 265, Purchases: 3},
+			Categories: map[string]Total{
+				CategorySupermarket: {Amount: 200, Purchases: 1},
+				CategoryRestaurants: {Amount: 65, Purchases: 2},
+			},
+		},
+		{
+			Month: "2024-03",
+			Total: Total{Amount: 500, Purchases: 4},
+			Categories: map[string]Total{
+				CategorySupermarket: {Amount: 300, Purchases: 1},
+				CategoryPharmacy:    {Amount: 80, Purchases: 1},
+				CategoryRestaurants: {Amount: 70, Purchases: 1},
+				CategoryOther:       {Amount: 50, Purchases: 1},
+			},
+		},
+	}, totals)
+}
+
+func TestTopMerchants(t *testing.T) {
+	assert.Equal(t, []MerchantTotal{
+		{Merchant: "שופרסל", Category: CategorySupermarket, Total: Total{Amount: 500, Purchases: 2}},
+		{Merchant: "סופר-פארם", Category: CategoryPharmacy, Total: Total{Amount: 80, Purchases: 1}},
+	}, TopMerchants(testPurchases(t), 2))
+
+	assert.Len(t, TopMerchants(testPurchases(t), 0), 6)
+}
+
+func TestTrends(t *testing.T) {
+	trends := Trends(testPurchases(t))
+
+	assert.Equal(t, []Trend{
+		{Category: CategoryOther, Month: "2024-02", Amount: 0, Previous: 0},
+		{Category: CategoryOther, Month: "2024-03", Amount: 50, Previous: 0},
+		{Category: CategoryPharmacy, Month: "2024-02", Amount: 0, Previous: 0},
+		{Category: CategoryPharmacy, Month: "2024-03", Amount: 80, Previous: 0},
+		{Category: CategoryRestaurants, Month: "2024-02", Amount: 65, Previous: 0},
+		{Category: CategoryRestaurants, Month: "2024-03", Amount: 70, Previous: 65},
+		{Category: CategorySupermarket, Month: "2024-02", Amount: 200, Previous: 0},
+		{Category: CategorySupermarket, Month: "2024-03", Amount: 300, Previous: 200},
+	}, trends)
+
+	change, ok := trends[7].Change()
+	assert.True(t, ok)
+	assert.InDelta(t, 0.5, change, 0.0001)
+
+	_, ok = trends[1].Change()
+	assert.False(t, ok)
+
+	assert.Nil(t, Trends(nil))
+}
+
+func TestDailyTotals(t *testing.T) {
+	restaurants := FilterCategory(testPurchases(t), CategoryRestaurants)
+	totals := DailyTotals(restaurants)
+
+	assert.Equal(t, []DailyTotal{
+		{Date: time.Date(2024, time.February, 5, 0, 0, 0, 0, time.UTC), Total: Total{Amount: 65, Purchases: 2}},
+		{Date: time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC), Total: Total{Amount: 70, Purchases: 1}},
+	}, totals)
+
+	assert.Equal(t, totals[1:], DaysAbove(totals, 65))
+}
@@ -0,0 +1,212 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+var ErrNoFactors = errors.New("the card has no factors")
+
+// A load from the history, with the factors tiers it fell into
+type LoadDiscount struct {
+	Item gohever.CardHistoryItem
+	Date time.Time
+
+	// The face value of the load and the price paid for it
+	Amount float64
+	Paid   float64
+
+	// The amount taken from every tier, ordered like the card's factors
+	Tiers []gohever.CardFactor
+}
+
+// The discount achieved on the load, e.g. 0.3 for 30%
+func (load LoadDiscount) Discount() float64 {
+	return discount(load.Amount, load.Paid)
+}
+
+// The loads of a month
+type MonthDiscount struct {
+	Month string // YYYY-MM
+	Loads []LoadDiscount
+
+	Loaded float64
+	Paid   float64
+
+	// The discount missed on the capacity of the tiers below the last one, which was left unused at
+	// the end of the month
+	LeftOnTable float64
+
+	// The amount left in every tier at the end of the month, ordered like the card's factors
+	Unused []gohever.CardFactor
+}
+
+// The discount achieved on the month's loads, e.g. 0.3 for 30%
+func (month MonthDiscount) Discount() float64 {
+	return discount(month.Loaded, month.Paid)
+}
+
+// A report of the discount achieved on the loads of a card
+type DiscountReport struct {
+	Factors []gohever.CardFactor
+	Months  []MonthDiscount
+
+	Loaded      float64
+	Paid        float64
+	LeftOnTable float64
+}
+
+// The discount achieved on all of the loads, e.g. 0.3 for 30%
+func (report *DiscountReport) Discount() float64 {
+	return discount(report.Loaded, report.Paid)
+}
+
+// Builds a discount report of the loads in the history, using the factors from the card's status.
+//
+// The tiers are filled by the loads of every month in order, the same way the site charges them,
+// so a load may span several tiers. The site doesn't tell the leftovers of previous months apart,
+// so they are counted as loads of the month. Anything above the tiers is charged by the last one.
+//
+// The discount left on the table of a month is the discount missed on the tiers below the last one
+// which are still unused at its end. A month which used up all of them leaves nothing on the table.
+func NewDiscountReport(items []gohever.CardHistoryItem, factors []gohever.CardFactor) (*DiscountReport, error) {
+	if len(factors) == 0 {
+		return nil, ErrNoFactors
+	}
+
+	var loads []LoadDiscount
+
+	for _, item := range items {
+		if item.ActionType != gohever.ActionLoad {
+			continue
+		}
+
+		date, err := item.ParseDate(time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.Id, err)
+		}
+
+		loads = append(loads, LoadDiscount{Item: item, Date: date, Amount: math.Abs(item.Amount)})
+	}
+
+	sort.SliceStable(loads, func(i, j int) bool {
+		return loads[i].Date.Before(loads[j].Date)
+	})
+
+	report := &DiscountReport{Factors: factors}
+
+	var (
+		month  *MonthDiscount
+		unused []gohever.CardFactor
+	)
+
+	for _, load := range loads {
+		if name := load.Date.Format("2006-01"); month == nil || month.Month != name {
+			report.closeMonth(month)
+
+			report.Months = append(report.Months, MonthDiscount{Month: name})
+			month = &report.Months[len(report.Months)-1]
+
+			// Every month starts with the tiers empty
+			unused = append([]gohever.CardFactor(nil), factors...)
+			month.Unused = unused
+		}
+
+		remaining := load.Amount
+
+		for i := range unused {
+			taken := min(remaining, unused[i].Amount)
+			if i == len(unused)-1 {
+				taken = remaining
+			}
+
+			unused[i].Amount = max(0, unused[i].Amount-taken)
+			remaining -= taken
+
+			load.Tiers = append(load.Tiers, gohever.CardFactor{Factor: unused[i].Factor, Amount: taken})
+			load.Paid += taken * unused[i].Factor
+		}
+
+		month.Loads = append(month.Loads, load)
+		month.Loaded += load.Amount
+		month.Paid += load.Paid
+
+		report.Loaded += load.Amount
+		report.Paid += load.Paid
+	}
+
+	report.closeMonth(month)
+
+	return report, nil
+}
+
+// Sums the discount left on the table of the month, once all of its loads are in
+func (report *DiscountReport) closeMonth(month *MonthDiscount) {
+	if month == nil {
+		return
+	}
+
+	// The last tier takes anything above the others, so only the ones below it can be left unused
+	for _, tier := range month.Unused[:len(month.Unused)-1] {
+		month.LeftOnTable += tier.Amount * (1 - tier.Factor)
+	}
+
+	report.LeftOnTable += month.LeftOnTable
+}
+
+// Writes the report as a text table, with a row for every load and a total for every month
+func (report *DiscountReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "FACTORS: %s\n", formatTiers(report.Factors))
+	fmt.Fprintln(tw, "DATE\tLOADED\tPAID\tDISCOUNT\tLEFT ON TABLE\tTIERS")
+
+	for _, month := range report.Months {
+		for _, load := range month.Loads {
+			fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.1f%%\t-\t%s\n",
+				load.Date.Format("02/01/2006"), load.Amount, load.Paid, load.Discount()*100,
+				formatTiers(load.Tiers))
+		}
+
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.1f%%\t%.2f\tunused %s\n",
+			month.Month, month.Loaded, month.Paid, month.Discount()*100, month.LeftOnTable,
+			formatTiers(month.Unused))
+	}
+
+	fmt.Fprintf(tw, "TOTAL\t%.2f\t%.2f\t%.1f%%\t%.2f\n",
+		report.Loaded, report.Paid, report.Discount()*100, report.LeftOnTable)
+
+	return tw.Flush()
+}
+
+// Formats the non-empty tiers, e.g. "1000.00 at 0.70, 500.00 at 0.75"
+func formatTiers(tiers []gohever.CardFactor) string {
+	var formatted []string
+	for _, tier := range tiers {
+		if tier.Amount > 0 {
+			formatted = append(formatted, fmt.Sprintf("%.2f at %.2f", tier.Amount, tier.Factor))
+		}
+	}
+
+	if len(formatted) == 0 {
+		return "-"
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func discount(amount, paid float64) float64 {
+	if amount == 0 {
+		return 0
+	}
+
+	return 1 - paid/amount
+}
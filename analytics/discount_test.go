@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+)
+
+var testFactors = []gohever.CardFactor{
+	{Factor: 0.7, Amount: 1000},
+	{Factor: 0.75, Amount: 1000},
+	{Factor: 0.8, Amount: 1000},
+}
+
+var testLoads = []gohever.CardHistoryItem{
+	{Id: "4", Date: "02/04/2024", ActionType: gohever.ActionLoad, Amount: 400},
+	{Id: "3", Date: "20/03/2024", ActionType: gohever.ActionLoad, Amount: 1500},
+	{Id: "2", Date: "04/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "שופרסל", Amount: 200},
+	{Id: "1", Date: "01/03/2024", ActionType: gohever.ActionLoad, Amount: 800},
+}
+
+func TestNewDiscountReport(t *testing.T) {
+	report, err := NewDiscountReport(testLoads, testFactors)
+	assert.NoError(t, err)
+
+	assert.Len(t, report.Months, 2)
+
+	march := report.Months[0]
+	assert.Equal(t, "2024-03", march.Month)
+	assert.Len(t, march.Loads, 2)
+
+	// The second load takes the rest of the first tier, and spans into the second one
+	assert.Equal(t, "3", march.Loads[1].Item.Id)
+	assert.Equal(t, []gohever.CardFactor{
+		{Factor: 0.7, Amount: 200},
+		{Factor: 0.75, Amount: 1000},
+		{Factor: 0.8, Amount: 300},
+	}, march.Loads[1].Tiers)
+	assert.InDelta(t, 1130, march.Loads[1].Paid, 0.001)
+
+	assert.InDelta(t, 2300, march.Loaded, 0.001)
+	assert.InDelta(t, 1690, march.Paid, 0.001)
+	assert.Equal(t, 0.0, march.LeftOnTable)
+	assert.Equal(t, []gohever.CardFactor{
+		{Factor: 0.7, Amount: 0},
+		{Factor: 0.75, Amount: 0},
+		{Factor: 0.8, Amount: 700},
+	}, march.Unused)
+
+	// A new month starts from the first tier again
+	april := report.Months[1]
+	assert.Equal(t, []gohever.CardFactor{
+		{Factor: 0.7, Amount: 400},
+		{Factor: 0.75, Amount: 0},
+		{Factor: 0.8, Amount: 0},
+	}, april.Loads[0].Tiers)
+	assert.InDelta(t, 0.3, april.Discount(), 0.0001)
+
+	assert.InDelta(t, 2700, report.Loaded, 0.001)
+	assert.InDelta(t, 1970, report.Paid, 0.001)
+	assert.InDelta(t, 430, report.LeftOnTable, 0.001)
+
+	// The factors of the card are not changed
+	assert.Equal(t, 1000.0, testFactors[0].Amount)
+}
+
+func TestNewDiscountReportAboveTiers(t *testing.T) {
+	report, err := NewDiscountReport([]gohever.CardHistoryItem{
+		{Id: "1", Date: "01/03/2024", ActionType: gohever.ActionLoad, Amount: 1500},
+	}, testFactors[:1])
+	assert.NoError(t, err)
+
+	assert.Equal(t, []gohever.CardFactor{{Factor: 0.7, Amount: 1500}}, report.Months[0].Loads[0].Tiers)
+	assert.InDelta(t, 1050, report.Paid, 0.001)
+}
+
+func TestNewDiscountReportLeftOnTable(t *testing.T) {
+	report, err := NewDiscountReport(testLoads, testFactors)
+	assert.NoError(t, err)
+
+	// March used up the tiers below the last one
+	assert.Equal(t, 0.0, report.Months[0].LeftOnTable)
+
+	// April left 600 at 0.7 and 1000 at 0.75 unused, the last tier doesn't count
+	assert.InDelta(t, 600*0.3+1000*0.25, report.Months[1].LeftOnTable, 0.001)
+	assert.InDelta(t, 430, report.LeftOnTable, 0.001)
+}
+
+func TestNewDiscountReportErrors(t *testing.T) {
+	_, err := NewDiscountReport(testLoads, nil)
+	assert.ErrorIs(t, err, ErrNoFactors)
+
+	_, err = NewDiscountReport([]gohever.CardHistoryItem{
+		{Id: "1", Date: "nope", ActionType: gohever.ActionLoad, Amount: 100},
+	}, testFactors)
+	assert.EqualError(t, err, `item 1: unable to parse date "nope"`)
+
+	report, err := NewDiscountReport(nil, testFactors)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Months)
+	assert.Equal(t, 0.0, report.Discount())
+}
+
+func TestDiscountReportWriteText(t *testing.T) {
+	report, _ := NewDiscountReport(testLoads, testFactors)
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteText(&out))
+
+	assert.Equal(t, `FACTORS: 1000.00 at 0.70, 1000.00 at 0.75, 1000.00 at 0.80
+DATE        LOADED   PAID     DISCOUNT  LEFT ON TABLE  TIERS
+01/03/2024  800.00   560.00   30.0%     -              800.00 at 0.70
+20/03/2024  1500.00  1130.00  24.7%     -              200.00 at 0.70, 1000.00 at 0.75, 300.00 at 0.80
+2024-03     2300.00  1690.00  26.5%     0.00           unused 700.00 at 0.80
+02/04/2024  400.00   280.00   30.0%     -              400.00 at 0.70
+2024-04     400.00   280.00   30.0%     430.00         unused 600.00 at 0.70, 1000.00 at 0.75, 1000.00 at 0.80
+TOTAL       2700.00  1970.00  27.0%     430.00
+`, out.String())
+}
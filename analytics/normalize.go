@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Branch details following the name, e.g. "שופרסל - רמת גן", "רמי לוי (חולון)" or "ארומה, דיזנגוף"
+	regexBranchSeparator = regexp.MustCompile(`\s+[-–—|]\s+|\s*[(,]`)
+
+	// The branch numbers or "branch" word, e.g. "סופר-פארם סניף 123" or "מקדונלדס #45"
+	regexBranchSuffix = regexp.MustCompile(`\s+(?:סניף.*|סנ'.*|#?\d+)$`)
+
+	// "Ltd." in Hebrew, in the common spellings
+	regexCompanySuffix = regexp.MustCompile(`\s+בע"?מ$`)
+)
+
+// Hebrew punctuation and typographic quotes, replaced by their plain counterparts
+var punctuationReplacer = strings.NewReplacer(
+	"\u05f4", `"`, "\u05f3", "'",
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`,
+	"\u2018", "'", "\u2019", "'",
+)
+
+// Normalizes the name of a business, so the variants of the same merchant group together: removes
+// invisible direction marks, collapses whitespace, unifies the Hebrew punctuation and drops the
+// branch details.
+func NormalizeBusinessName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u200b' || r == '\ufeff' || r == '\u200e' || r == '\u200f' || (r >= '\u202a' && r <= '\u202e'):
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		}
+
+		return r
+	}, name)
+
+	name = punctuationReplacer.Replace(name)
+	name = strings.Join(strings.Fields(name), " ")
+
+	if loc := regexBranchSeparator.FindStringIndex(name); loc != nil && loc[0] > 0 {
+		name = name[:loc[0]]
+	}
+
+	// A number alone is the name, not a branch
+	if stripped := regexBranchSuffix.ReplaceAllString(name, ""); stripped != "" {
+		name = stripped
+	}
+
+	name = regexCompanySuffix.ReplaceAllString(name, "")
+
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,32 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeBusinessName(t *testing.T) {
+	tests := map[string]string{
+		"שופרסל דיל":             "שופרסל דיל",
+		"  שופרסל   דיל  ":       "שופרסל דיל",
+		"שופרסל\u00a0דיל":        "שופרסל דיל",
+		"\u200fשופרסל דיל\u200e": "שופרסל דיל",
+		"שופרסל דיל - רמת גן":    "שופרסל דיל",
+		"רמי לוי (חולון)":        "רמי לוי",
+		"ארומה, דיזנגוף סנטר":    "ארומה",
+		"סופר-פארם סניף 123":     "סופר-פארם",
+		"סופר-פארם סנ' גבעתיים":  "סופר-פארם",
+		"מקדונלד\u05f3ס #45":     "מקדונלד'ס",
+		"יינות ביתן בע\u05f4מ":   "יינות ביתן",
+		"יינות ביתן בעמ 12":      "יינות ביתן",
+		"McDonalds - Dizengoff":  "mcdonalds",
+		"365":                    "365",
+		"Wolt | Tel Aviv":        "wolt",
+		"(ללא שם)":               "(ללא שם)",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, NormalizeBusinessName(name), name)
+	}
+}
@@ -0,0 +1,122 @@
+package analytics
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// The categories of the default rules
+const (
+	CategorySupermarket = "supermarket"
+	CategoryRestaurants = "restaurants"
+	CategoryPharmacy    = "pharmacy"
+
+	// Merchants not matching any rule
+	CategoryOther = "other"
+)
+
+// The default rules, a starting point for a rules file of your own
+//
+//go:embed rules.json
+var DefaultRulesFile []byte
+
+// Rules for grouping merchants and assigning them categories. A rules file looks like:
+//
+//	{
+//	  "aliases": {"שופרסל דיל": "שופרסל"},
+//	  "categories": [
+//	    {"name": "supermarket", "merchants": ["שופרסל", "רמי לוי"]},
+//	    {"name": "restaurants", "merchants": ["מסעד", "פיצה"]}
+//	  ]
+//	}
+//
+// Aliases group variants of a merchant under a single name. A merchant belongs to the first
+// category with a merchant matching the start of any of its words, so "מסעד" matches "מסעדת הדג".
+// Names are normalized by NormalizeBusinessName before matching.
+type Rules struct {
+	Aliases    map[string]string `json:"aliases"`
+	Categories []CategoryRule    `json:"categories"`
+}
+
+type CategoryRule struct {
+	Name      string   `json:"name"`
+	Merchants []string `json:"merchants"`
+}
+
+// Returns the default rules, see DefaultRulesFile
+func DefaultRules() *Rules {
+	rules, err := ParseRules(bytes.NewReader(DefaultRulesFile))
+	if err != nil {
+		panic(err)
+	}
+
+	return rules
+}
+
+// Loads rules from a file
+func LoadRules(fileName string) (*Rules, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return ParseRules(file)
+}
+
+func ParseRules(r io.Reader) (*Rules, error) {
+	var rules Rules
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("unable to parse the rules: %w", err)
+	}
+
+	// Normalize the rules once, so they match the normalized names
+	aliases := make(map[string]string, len(rules.Aliases))
+	for alias, merchant := range rules.Aliases {
+		aliases[NormalizeBusinessName(alias)] = NormalizeBusinessName(merchant)
+	}
+
+	rules.Aliases = aliases
+
+	for i, category := range rules.Categories {
+		if category.Name == "" {
+			return nil, fmt.Errorf("unable to parse the rules: category %d has no name", i+1)
+		}
+
+		for j, merchant := range category.Merchants {
+			category.Merchants[j] = NormalizeBusinessName(merchant)
+		}
+	}
+
+	return &rules, nil
+}
+
+// Returns the merchant of a business name, normalized and with the aliases applied
+func (rules *Rules) Merchant(businessName string) string {
+	merchant := NormalizeBusinessName(businessName)
+
+	if alias, ok := rules.Aliases[merchant]; ok {
+		return alias
+	}
+
+	return merchant
+}
+
+// Returns the category of a merchant, or CategoryOther
+func (rules *Rules) Category(merchant string) string {
+	for _, category := range rules.Categories {
+		for _, pattern := range category.Merchants {
+			if pattern != "" && (strings.HasPrefix(merchant, pattern) || strings.Contains(merchant, " "+pattern)) {
+				return category.Name
+			}
+		}
+	}
+
+	return CategoryOther
+}
@@ -0,0 +1,251 @@
+// Package archive keeps the history and status snapshots of cards in a local SQLite database, so
+// they outlive the few months the site shows.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yardnsm/gohever"
+
+	// A pure-Go SQLite driver, no cgo needed
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS history (
+	card_type     TEXT NOT NULL,
+	id            TEXT NOT NULL,
+	date          TEXT NOT NULL, -- YYYY-MM-DD, for querying
+	site_date     TEXT NOT NULL, -- as shown by the site
+	action        TEXT NOT NULL,
+	business_name TEXT NOT NULL,
+	amount        REAL NOT NULL,
+
+	PRIMARY KEY (card_type, id)
+);
+
+CREATE INDEX IF NOT EXISTS history_date ON history (date);
+
+CREATE TABLE IF NOT EXISTS statuses (
+	card_type TEXT NOT NULL,
+	time      TEXT NOT NULL, -- RFC 3339, in UTC
+	status    TEXT NOT NULL, -- the CardStatus as JSON
+
+	PRIMARY KEY (card_type, time)
+);
+`
+
+// An archive of the history and statuses of cards
+type Archive struct {
+	db *sql.DB
+}
+
+// A status of a card at a given time
+type Snapshot struct {
+	CardType gohever.CardType
+	Time     time.Time
+	Status   gohever.CardStatus
+}
+
+// Opens the archive in the given file, creating it if needed
+func Open(fileName string) (*Archive, error) {
+	db, err := sql.Open("sqlite", "file:"+fileName+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	// Writes are serialized by SQLite anyway
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create the archive schema: %w", err)
+	}
+
+	return &Archive{db: db}, nil
+}
+
+func (archive *Archive) Close() error {
+	return archive.db.Close()
+}
+
+// Saves the history items of a card, updating the ones already archived. Returns the number of
+// new items. Items without an ID from the site are skipped, as their IDs aren't stable.
+func (archive *Archive) SaveHistory(ctx context.Context, cardType gohever.CardType, items []gohever.CardHistoryItem) (int, error) {
+	tx, err := archive.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	added := 0
+
+	for _, item := range items {
+		if !item.HasId() {
+			continue
+		}
+
+		date, err := item.ParseDate(time.UTC)
+		if err != nil {
+			return 0, fmt.Errorf("item %s: %w", item.Id, err)
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM history WHERE card_type = ? AND id = ?)`,
+			cardType.String(), item.Id).Scan(&exists)
+
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO history (card_type, id, date, site_date, action, business_name, amount)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (card_type, id) DO UPDATE SET
+				date = excluded.date,
+				site_date = excluded.site_date,
+				action = excluded.action,
+				business_name = excluded.business_name,
+				amount = excluded.amount`,
+			cardType.String(), item.Id, date.Format(time.DateOnly), item.Date,
+			item.ActionType.String(), item.BusinessName, item.Amount)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if !exists {
+			added++
+		}
+	}
+
+	return added, tx.Commit()
+}
+
+// Saves a snapshot of the status of a card. The serial number is not kept.
+func (archive *Archive) SaveStatus(ctx context.Context, cardType gohever.CardType, status gohever.CardStatus, at time.Time) error {
+	status.SerialNumber = ""
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	_, err = archive.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO statuses (card_type, time, status) VALUES (?, ?, ?)`,
+		cardType.String(), at.UTC().Format(time.RFC3339), string(data))
+
+	return err
+}
+
+// Returns the archived history of a card, oldest first
+func (archive *Archive) History(ctx context.Context, cardType gohever.CardType) ([]gohever.CardHistoryItem, error) {
+	rows, err := archive.db.QueryContext(ctx, `
+		SELECT id, site_date, action, business_name, amount FROM history
+		WHERE card_type = ?
+		ORDER BY date, id`, cardType.String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var items []gohever.CardHistoryItem
+
+	for rows.Next() {
+		var item gohever.CardHistoryItem
+		var action string
+
+		if err := rows.Scan(&item.Id, &item.Date, &action, &item.BusinessName, &item.Amount); err != nil {
+			return nil, err
+		}
+
+		if err := item.ActionType.UnmarshalText([]byte(action)); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Returns the archived status snapshots of a card, oldest first
+func (archive *Archive) Snapshots(ctx context.Context, cardType gohever.CardType) ([]Snapshot, error) {
+	rows, err := archive.db.QueryContext(ctx,
+		`SELECT time, status FROM statuses WHERE card_type = ? ORDER BY time`, cardType.String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var snapshots []Snapshot
+
+	for rows.Next() {
+		var at, data string
+		if err := rows.Scan(&at, &data); err != nil {
+			return nil, err
+		}
+
+		snapshot := Snapshot{CardType: cardType}
+
+		if snapshot.Time, err = time.Parse(time.RFC3339, at); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(data), &snapshot.Status); err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// The result of syncing a card
+type SyncResult struct {
+	CardType gohever.CardType
+
+	// The number of new history items
+	Added int
+}
+
+// Archives the current history and a status snapshot of every card
+func (archive *Archive) Sync(ctx context.Context, cards []gohever.CardInterface, at time.Time) ([]SyncResult, error) {
+	var results []SyncResult
+
+	for _, card := range cards {
+		history, err := card.GetHistoryContext(ctx)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		added, err := archive.SaveHistory(ctx, card.Type(), *history)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		status, err := card.GetStatusContext(ctx)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		if err := archive.SaveStatus(ctx, card.Type(), *status, at); err != nil {
+			return results, fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		results = append(results, SyncResult{CardType: card.Type(), Added: added})
+	}
+
+	return results, nil
+}
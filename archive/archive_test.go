@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/analytics"
+	"github.com/yardnsm/gohever/gohevertest"
+)
+
+func setupArchive(t *testing.T) *Archive {
+	archive, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { archive.Close() })
+
+	return archive
+}
+
+func TestSaveHistory(t *testing.T) {
+	archive := setupArchive(t)
+	ctx := context.Background()
+
+	items := []gohever.CardHistoryItem{
+		{Id: "1", Date: "01/03/2024", ActionType: gohever.ActionLoad, Amount: 1000},
+		{Id: "2", Date: "14/03/2024 12:30", ActionType: gohever.ActionPurchase, BusinessName: "שופרסל", Amount: 52.5},
+		{Id: "no_id_2", Date: "15/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "???", Amount: 1},
+	}
+
+	added, err := archive.SaveHistory(ctx, gohever.TypeKeva, items)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	// Saving again updates the existing items
+	items[1].Amount = 55
+	items = append(items, gohever.CardHistoryItem{Id: "3", Date: "02/04/2024", ActionType: gohever.ActionPurchase, BusinessName: "רמי לוי", Amount: 20})
+
+	added, err = archive.SaveHistory(ctx, gohever.TypeKeva, items)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	history, err := archive.History(ctx, gohever.TypeKeva)
+	assert.NoError(t, err)
+	assert.Equal(t, []gohever.CardHistoryItem{items[0], items[1], items[3]}, history)
+
+	// The same IDs on another card are other items
+	added, err = archive.SaveHistory(ctx, gohever.TypeTeamim, items[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	_, err = archive.SaveHistory(ctx, gohever.TypeKeva, []gohever.CardHistoryItem{{Id: "4", Date: "yesterday"}})
+	assert.EqualError(t, err, `item 4: unable to parse date "yesterday"`)
+}
+
+func TestSnapshots(t *testing.T) {
+	archive := setupArchive(t)
+	ctx := context.Background()
+
+	status := gohevertest.DefaultStatus()
+	at := time.Date(2024, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, archive.SaveStatus(ctx, gohever.TypeKeva, status, at))
+
+	snapshots, err := archive.Snapshots(ctx, gohever.TypeKeva)
+	assert.NoError(t, err)
+
+	// Without the serial number
+	status.SerialNumber = ""
+	assert.Equal(t, []Snapshot{{CardType: gohever.TypeKeva, Time: at, Status: status}}, snapshots)
+}
+
+func TestSync(t *testing.T) {
+	archive := setupArchive(t)
+	ctx := context.Background()
+
+	now := func() time.Time { return time.Date(2024, time.March, 14, 12, 0, 0, 0, time.UTC) }
+
+	keva := gohevertest.NewCard(gohever.TypeKeva, gohevertest.DefaultStatus())
+	keva.Now = now
+	keva.Load(gohevertest.DefaultStatus(), 1000)
+	keva.Purchase("שופרסל", 300)
+
+	teamim := gohevertest.NewCard(gohever.TypeTeamim, gohevertest.DefaultStatus())
+	teamim.Now = now
+
+	results, err := archive.Sync(ctx, []gohever.CardInterface{keva, teamim}, now())
+	assert.NoError(t, err)
+	assert.Equal(t, []SyncResult{{CardType: gohever.TypeKeva, Added: 2}, {CardType: gohever.TypeTeamim, Added: 0}}, results)
+
+	snapshots, _ := archive.Snapshots(ctx, gohever.TypeKeva)
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, 700.0, snapshots[0].Status.CurrentBalance)
+
+	keva.SetError(gohevertest.OpGetHistory, gohever.ErrNotAuthenticated)
+
+	_, err = archive.Sync(ctx, []gohever.CardInterface{keva}, now())
+	assert.ErrorIs(t, err, gohever.ErrNotAuthenticated)
+}
+
+func TestMonthlySpend(t *testing.T) {
+	archive := setupArchive(t)
+	ctx := context.Background()
+
+	archive.SaveHistory(ctx, gohever.TypeKeva, []gohever.CardHistoryItem{
+		{Id: "1", Date: "01/03/2024", ActionType: gohever.ActionLoad, Amount: 1000},
+		{Id: "2", Date: "02/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "שופרסל ", Amount: 100},
+		{Id: "3", Date: "09/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "שופרסל", Amount: 150},
+		{Id: "4", Date: "10/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "רמי לוי", Amount: 300},
+		{Id: "5", Date: "02/04/2024", ActionType: gohever.ActionPurchase, BusinessName: "רמי לוי", Amount: 50},
+	})
+
+	archive.SaveHistory(ctx, gohever.TypeTeamim, []gohever.CardHistoryItem{
+		{Id: "1", Date: "05/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "פיצה", Amount: 60},
+	})
+
+	spends, err := archive.MonthlySpend(ctx, ForCard(gohever.TypeKeva))
+	assert.NoError(t, err)
+	assert.Equal(t, []BusinessSpend{
+		{CardType: gohever.TypeKeva, Month: "2024-03", BusinessName: "רמי לוי", Total: 300, Purchases: 1},
+		{CardType: gohever.TypeKeva, Month: "2024-03", BusinessName: "שופרסל", Total: 250, Purchases: 2},
+		{CardType: gohever.TypeKeva, Month: "2024-04", BusinessName: "רמי לוי", Total: 50, Purchases: 1},
+	}, spends)
+
+	spends, err = archive.MonthlySpend(ctx, Since(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)))
+	assert.NoError(t, err)
+	assert.Len(t, spends, 4)
+	assert.Equal(t, gohever.TypeTeamim, spends[2].CardType)
+}
+
+func TestDiscounts(t *testing.T) {
+	archive := setupArchive(t)
+	ctx := context.Background()
+
+	archive.SaveHistory(ctx, gohever.TypeKeva, []gohever.CardHistoryItem{
+		{Id: "1", Date: "01/02/2024", ActionType: gohever.ActionLoad, Amount: 500},
+		{Id: "2", Date: "01/03/2024", ActionType: gohever.ActionLoad, Amount: 1000},
+		{Id: "3", Date: "15/03/2024", ActionType: gohever.ActionLoad, Amount: 1000},
+		{Id: "4", Date: "16/03/2024", ActionType: gohever.ActionPurchase, Amount: 1000},
+	})
+
+	// No snapshots, nothing to calculate by
+	discounts, err := archive.MonthlyDiscounts(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, discounts)
+
+	archive.SaveStatus(ctx, gohever.TypeKeva, gohevertest.DefaultStatus(), time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC))
+
+	discounts, err = archive.MonthlyDiscounts(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []MonthlyDiscount{
+		{CardType: gohever.TypeKeva, Month: "2024-02", Loaded: 500, Paid: 350},
+		{CardType: gohever.TypeKeva, Month: "2024-03", Loaded: 2000, Paid: 1450},
+	}, discounts)
+
+	assert.InDelta(t, 0.275, discounts[1].Discount(), 0.0001)
+
+	average, err := archive.AverageDiscount(ctx)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1-1800.0/2500, average, 0.0001)
+}
+
+func TestDiscountsMatchReport(t *testing.T) {
+	archive := setupArchive(t)
+	ctx := context.Background()
+
+	status := gohevertest.DefaultStatus()
+
+	// Loads may be listed with a negative amount, the report counts them by their value
+	items := []gohever.CardHistoryItem{
+		{Id: "1", Date: "01/03/2024", ActionType: gohever.ActionLoad, Amount: 1500},
+		{Id: "2", Date: "09/03/2024", ActionType: gohever.ActionLoad, Amount: -1000},
+		{Id: "3", Date: "02/04/2024", ActionType: gohever.ActionLoad, Amount: 400},
+	}
+
+	archive.SaveHistory(ctx, gohever.TypeKeva, items)
+	archive.SaveStatus(ctx, gohever.TypeKeva, status, time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC))
+
+	discounts, err := archive.MonthlyDiscounts(ctx)
+	require.NoError(t, err)
+
+	report, err := analytics.NewDiscountReport(items, status.Factors)
+	require.NoError(t, err)
+
+	require.Len(t, discounts, len(report.Months))
+	for i, month := range report.Months {
+		assert.Equal(t, month.Month, discounts[i].Month)
+		assert.InDelta(t, month.Loaded, discounts[i].Loaded, 0.001)
+		assert.InDelta(t, month.Paid, discounts[i].Paid, 0.001)
+	}
+
+	assert.InDelta(t, 1850, discounts[0].Paid, 0.001)
+}
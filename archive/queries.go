@@ -0,0 +1,251 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/analytics"
+)
+
+// An option for the queries
+type QueryOption func(options *queryOptions)
+
+type queryOptions struct {
+	cardType *gohever.CardType
+	since    string
+}
+
+func newQueryOptions(opts []QueryOption) queryOptions {
+	var options queryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// The conditions of the options, to append to a WHERE clause
+func (options queryOptions) where() (string, []any) {
+	var conditions []string
+	var args []any
+
+	if options.cardType != nil {
+		conditions = append(conditions, "card_type = ?")
+		args = append(args, options.cardType.String())
+	}
+
+	if options.since != "" {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, options.since)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// Only query the given card
+func ForCard(cardType gohever.CardType) QueryOption {
+	return func(options *queryOptions) {
+		options.cardType = &cardType
+	}
+}
+
+// Only query the history since the given date
+func Since(date time.Time) QueryOption {
+	return func(options *queryOptions) {
+		options.since = date.Format(time.DateOnly)
+	}
+}
+
+// The spend in a business during a month
+type BusinessSpend struct {
+	CardType     gohever.CardType
+	Month        string // YYYY-MM
+	BusinessName string
+	Total        float64
+	Purchases    int
+}
+
+// Returns the monthly spend per business, by month and then by the highest spend
+func (archive *Archive) MonthlySpend(ctx context.Context, opts ...QueryOption) ([]BusinessSpend, error) {
+	where, args := newQueryOptions(opts).where()
+
+	rows, err := archive.db.QueryContext(ctx, `
+		SELECT card_type, substr(date, 1, 7) AS month, trim(business_name), SUM(amount), COUNT(*)
+		FROM history
+		WHERE action = 'purchase'`+where+`
+		GROUP BY card_type, month, trim(business_name)
+		ORDER BY month, SUM(amount) DESC, trim(business_name)`, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var spends []BusinessSpend
+
+	for rows.Next() {
+		var spend BusinessSpend
+		var cardType string
+
+		if err := rows.Scan(&cardType, &spend.Month, &spend.BusinessName, &spend.Total, &spend.Purchases); err != nil {
+			return nil, err
+		}
+
+		if err := spend.CardType.UnmarshalText([]byte(cardType)); err != nil {
+			return nil, err
+		}
+
+		spends = append(spends, spend)
+	}
+
+	return spends, rows.Err()
+}
+
+// The loads of a card during a month, and what they cost after the discount
+type MonthlyDiscount struct {
+	CardType gohever.CardType
+	Month    string // YYYY-MM
+
+	Loaded float64
+	Paid   float64
+}
+
+// The discount achieved, e.g. 0.25 for 25%
+func (discount MonthlyDiscount) Discount() float64 {
+	if discount.Loaded == 0 {
+		return 0
+	}
+
+	return 1 - discount.Paid/discount.Loaded
+}
+
+// Returns the discount achieved on the loads of every month, as calculated by
+// analytics.NewDiscountReport. The loads are charged by the factors of the card's snapshot closest
+// to the month, so months can only be calculated once there's a snapshot of the card with factors.
+func (archive *Archive) MonthlyDiscounts(ctx context.Context, opts ...QueryOption) ([]MonthlyDiscount, error) {
+	where, args := newQueryOptions(opts).where()
+
+	rows, err := archive.db.QueryContext(ctx, `
+		SELECT card_type, substr(date, 1, 7) AS month, id, site_date, amount
+		FROM history
+		WHERE action = 'load'`+where+`
+		ORDER BY month, card_type, date, id`, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		discounts []MonthlyDiscount
+		loads     [][]gohever.CardHistoryItem
+	)
+
+	for rows.Next() {
+		var discount MonthlyDiscount
+		var cardType string
+
+		item := gohever.CardHistoryItem{ActionType: gohever.ActionLoad}
+
+		if err := rows.Scan(&cardType, &discount.Month, &item.Id, &item.Date, &item.Amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if err := discount.CardType.UnmarshalText([]byte(cardType)); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if last := len(discounts) - 1; last < 0 || discounts[last] != discount {
+			discounts = append(discounts, discount)
+			loads = append(loads, nil)
+		}
+
+		loads[len(loads)-1] = append(loads[len(loads)-1], item)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	snapshots := map[gohever.CardType][]Snapshot{}
+	var calculated []MonthlyDiscount
+
+	for i, discount := range discounts {
+		if _, ok := snapshots[discount.CardType]; !ok {
+			if snapshots[discount.CardType], err = archive.Snapshots(ctx, discount.CardType); err != nil {
+				return nil, err
+			}
+		}
+
+		snapshot, ok := closestSnapshot(snapshots[discount.CardType], discount.Month)
+		if !ok {
+			continue
+		}
+
+		report, err := analytics.NewDiscountReport(loads[i], snapshot.Status.Factors)
+		if errors.Is(err, analytics.ErrNoFactors) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		discount.Loaded = report.Loaded
+		discount.Paid = report.Paid
+		calculated = append(calculated, discount)
+	}
+
+	return calculated, nil
+}
+
+// Returns the average discount achieved on all of the loads, weighted by their amounts
+func (archive *Archive) AverageDiscount(ctx context.Context, opts ...QueryOption) (float64, error) {
+	discounts, err := archive.MonthlyDiscounts(ctx, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	total := MonthlyDiscount{}
+	for _, discount := range discounts {
+		total.Loaded += discount.Loaded
+		total.Paid += discount.Paid
+	}
+
+	return total.Discount(), nil
+}
+
+// Returns the last snapshot taken until the end of the month, or the first one after it
+func closestSnapshot(snapshots []Snapshot, month string) (Snapshot, bool) {
+	if len(snapshots) == 0 {
+		return Snapshot{}, false
+	}
+
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return Snapshot{}, false
+	}
+
+	end := start.AddDate(0, 1, 0)
+
+	// The snapshots are ordered by their time
+	i := sort.Search(len(snapshots), func(i int) bool {
+		return !snapshots[i].Time.Before(end)
+	})
+
+	if i == 0 {
+		return snapshots[0], true
+	}
+
+	return snapshots[i-1], true
+}
@@ -1,9 +1,10 @@
 package gohever
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -33,23 +34,20 @@ func newAuth(hvr *Client) *Auth {
 	return auth
 }
 
-func wrapAuthenticated[T any](hvr *Client, handler requestHandler[T]) requestHandler[T] {
+func wrapAuthenticated[T any](ctx context.Context, hvr *Client, handler requestHandler[T]) requestHandler[T] {
 	// Run the request handler. If we're getting any unauthenticated response, then initiate the
 	// signin handler. It that fails, then... throw.
 
 	return func() (*T, error) {
-		if !hvr.isAuthenticated {
-			if err := hvr.Auth.Authenticate(); err != nil {
-				return nil, err
-			}
+		if err := hvr.ensureAuthenticated(ctx); err != nil {
+			return nil, err
 		}
 
+		session := hvr.session()
 		result, err := handler()
 
 		if errors.Is(err, ErrNotAuthenticated) {
-			hvr.isAuthenticated = false
-
-			if err := hvr.Auth.Authenticate(); err != nil {
+			if err := hvr.reauthenticate(ctx, session); err != nil {
 				return nil, err
 			}
 
@@ -60,7 +58,7 @@ func wrapAuthenticated[T any](hvr *Client, handler requestHandler[T]) requestHan
 	}
 }
 
-func parseGetConfigResponse(resp *resty.Response, credentials Credentials) (*authenticationConfig, error) {
+func parseGetConfigResponse(resp *resty.Response, credentials Credentials, selectors Selectors) (*authenticationConfig, error) {
 	doc, err := goquery.NewDocumentFromReader(resp.RawBody())
 	if err != nil {
 		return nil, err
@@ -72,7 +70,7 @@ func parseGetConfigResponse(resp *resty.Response, credentials Credentials) (*aut
 	}
 
 	// Populate formData
-	doc.Find("form#signinForm input[type=hidden]").Each(func(i int, s *goquery.Selection) {
+	doc.Find(selectors.LoginForm + " input[type=hidden]").Each(func(i int, s *goquery.Selection) {
 		key, exists := s.Attr("name")
 		val := s.AttrOr("value", "")
 
@@ -99,20 +97,28 @@ func parseGetConfigResponse(resp *resty.Response, credentials Credentials) (*aut
 	return config, nil
 }
 
-func parseAuthenticationResponse(resp *resty.Response) error {
+func parseAuthenticationResponse(resp *resty.Response, selectors Selectors) error {
+	if resp.StatusCode() != 200 {
+		return ErrAuthenticatedFailed
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+	if err != nil {
+		return err
+	}
+
 	// There are probably better ways to check if auth was successful...
-	authenticatedMatch, _ := regexp.Match("id=\"msg3\"", resp.Body())
-	if resp.StatusCode() != 200 || authenticatedMatch {
+	if doc.Find(selectors.LoginFailed).Length() > 0 {
 		return ErrAuthenticatedFailed
 	}
 
 	return nil
 }
 
-func (auth *Auth) getConfig() (*authenticationConfig, error) {
-	resp, err := auth.hvr.newRequest().
-		SetDoNotParseResponse(true).
-		Get(urlAuthenticate)
+func (auth *Auth) getConfig(ctx context.Context) (*authenticationConfig, error) {
+	resp, err := auth.hvr.execute(ctx, endpointAuthConfig,
+		auth.hvr.newRequest().SetDoNotParseResponse(true),
+		resty.MethodGet, auth.hvr.flavor.loginPath())
 
 	if err != nil {
 		return nil, err
@@ -123,49 +129,86 @@ func (auth *Auth) getConfig() (*authenticationConfig, error) {
 		return nil, fmt.Errorf("unable to get credentials from config: %w", err)
 	}
 
-	return parseGetConfigResponse(resp, credentials)
+	config, err := parseGetConfigResponse(resp, credentials, auth.hvr.selectors)
+	if err != nil {
+		auth.hvr.logParseFailure(ctx, endpointAuthConfig, err)
+		return nil, err
+	}
+
+	return config, nil
 }
 
-func (auth *Auth) sendVerifyPixel(config *authenticationConfig) error {
-	_, err := auth.hvr.newRequest().
-		Get(config.verifyPixelUrl)
+func (auth *Auth) sendVerifyPixel(ctx context.Context, config *authenticationConfig) error {
+	_, err := auth.hvr.execute(ctx, endpointAuthPixel,
+		auth.hvr.newRequest(),
+		resty.MethodGet, config.verifyPixelUrl)
 
 	return err
 }
 
-func (auth *Auth) Authenticate() error {
-	config, err := auth.getConfig()
+func (auth *Auth) authenticate(ctx context.Context) error {
+	config, err := auth.getConfig(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = auth.sendVerifyPixel(config)
+	err = auth.sendVerifyPixel(ctx, config)
 	if err != nil {
 		return err
 	}
 
-	resp, err := auth.hvr.newRequest().
-		SetFormData(config.formData).
-		Post(urlAuthenticate)
+	resp, err := auth.hvr.execute(ctx, endpointAuthenticate,
+		auth.hvr.newRequest().SetFormData(config.formData),
+		resty.MethodPost, auth.hvr.flavor.loginPath())
 
 	if err != nil {
 		return err
 	}
 
-	err = parseAuthenticationResponse(resp)
+	err = parseAuthenticationResponse(resp, auth.hvr.selectors)
 	if err != nil {
 		return err
 	}
 
-	auth.hvr.isAuthenticated = true
+	auth.hvr.setAuthenticated(true)
 	return nil
 }
 
+func (auth *Auth) AuthenticateContext(ctx context.Context) (err error) {
+	ctx, span := auth.hvr.telemetry.startSpan(ctx, "Auth.Authenticate")
+	defer func() { endSpan(span, err) }()
+
+	auth.hvr.logger.DebugContext(ctx, "authentication started")
+
+	err = auth.authenticate(ctx)
+	auth.hvr.telemetry.recordLogin(ctx, err)
+
+	if err != nil {
+		auth.hvr.logger.WarnContext(ctx, "authentication failed", errorAttr(err))
+		return err
+	}
+
+	auth.hvr.logger.InfoContext(ctx, "authenticated")
+	return nil
+}
+
+func (auth *Auth) Authenticate() error {
+	return auth.AuthenticateContext(context.Background())
+}
+
 func (auth *Auth) Deauthenticate() error {
-	_, err := auth.hvr.newRequest().
-		Get(urlDeauthenticate)
+	return auth.DeauthenticateContext(context.Background())
+}
+
+func (auth *Auth) DeauthenticateContext(ctx context.Context) (err error) {
+	ctx, span := auth.hvr.telemetry.startSpan(ctx, "Auth.Deauthenticate")
+	defer func() { endSpan(span, err) }()
+
+	_, err = auth.hvr.execute(ctx, endpointDeauthenticate,
+		auth.hvr.newRequest(),
+		resty.MethodGet, auth.hvr.flavor.logoutPath())
 
-	auth.hvr.isAuthenticated = false
+	auth.hvr.setAuthenticated(false)
 
 	return err
 }
@@ -1,6 +1,7 @@
 package gohever
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,7 +14,7 @@ func TestWrapAuthenticated(t *testing.T) {
 	type testStruct struct{ key string }
 
 	setupTest := func(t *testing.T) (*Client, *mocks.AuthInterface, *mocks.RequestHandler[testStruct]) {
-		client := NewClient(FlavorHvr, Config{})
+		client := NewClient(FlavorHvr(), Config{})
 
 		authMock := mocks.NewAuthInterface(t)
 		requestHandlerMock := mocks.NewRequestHandler[testStruct](t)
@@ -35,7 +36,7 @@ func TestWrapAuthenticated(t *testing.T) {
 		authMock.On("Authenticate").Once().Return(nil)
 		handler.On("Execute").Return(nil, nil) // Handler does nothing
 
-		wrapAuthenticated(client, handler.Execute)()
+		wrapAuthenticated(context.Background(), client, handler.Execute)()
 	})
 
 	t.Run("should not authenticate when the handler return data", func(t *testing.T) {
@@ -44,7 +45,7 @@ func TestWrapAuthenticated(t *testing.T) {
 		// Handler returns something, and no error
 		handler.On("Execute").Return(&testStruct{key: "value"}, nil)
 
-		data, err := wrapAuthenticated(client, handler.Execute)()
+		data, err := wrapAuthenticated(context.Background(), client, handler.Execute)()
 
 		authMock.AssertNumberOfCalls(t, "Authenticate", 0)
 
@@ -58,7 +59,7 @@ func TestWrapAuthenticated(t *testing.T) {
 		// Handler returns something, and an error (something random for the sake of it)
 		handler.On("Execute").Return(nil, ErrRedirectIsNotAllowed)
 
-		_, err := wrapAuthenticated(client, handler.Execute)()
+		_, err := wrapAuthenticated(context.Background(), client, handler.Execute)()
 
 		authMock.AssertNumberOfCalls(t, "Authenticate", 0)
 
@@ -74,7 +75,7 @@ func TestWrapAuthenticated(t *testing.T) {
 
 		authMock.On("Authenticate").Once().Return(nil)
 
-		data, err := wrapAuthenticated(client, handler.Execute)()
+		data, err := wrapAuthenticated(context.Background(), client, handler.Execute)()
 
 		assert.Equal(t, data, &testStruct{key: "value"})
 		assert.Equal(t, err, nil)
@@ -88,7 +89,7 @@ func TestWrapAuthenticated(t *testing.T) {
 
 		authMock.On("Authenticate").Once().Return(ErrAuthenticatedFailed)
 
-		_, err := wrapAuthenticated(client, handler.Execute)()
+		_, err := wrapAuthenticated(context.Background(), client, handler.Execute)()
 
 		assert.ErrorIs(t, err, ErrAuthenticatedFailed)
 	})
@@ -103,7 +104,7 @@ func TestGetConfig(t *testing.T) {
 
 	auth := newAuth(client)
 
-	cfg, err := auth.getConfig()
+	cfg, err := auth.getConfig(context.Background())
 
 	assert.Equal(t, err, nil)
 	assert.Equal(t, cfg, &authenticationConfig{
@@ -132,7 +133,7 @@ func TestSendVerifyPixel(t *testing.T) {
 
 	auth := newAuth(client)
 
-	err := auth.sendVerifyPixel(&authenticationConfig{
+	err := auth.sendVerifyPixel(context.Background(), &authenticationConfig{
 		verifyPixelUrl: "acmplt.asmx/logo?t=1234123412341",
 	})
 
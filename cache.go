@@ -0,0 +1,250 @@
+package gohever
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConfigTTL  = time.Hour
+	defaultBalanceTTL = time.Minute
+)
+
+type CacheOptions struct {
+	// How long to keep the card config: the factors, the limits and the serial number. Defaults
+	// to an hour.
+	ConfigTTL time.Duration
+
+	// How long to keep the card balance. Defaults to a minute.
+	BalanceTTL time.Duration
+}
+
+// CachedCard is a CardInterface caching the status of another card. The card config, which comes
+// from the heavy card page, is kept for a long time, while the balance is kept for a short time.
+// A successful load or management action invalidates the cache.
+//
+// Cards which are not created by this package can be cached as well, but only the complete status
+// will be cached, using the BalanceTTL.
+type CachedCard struct {
+	card    CardInterface
+	options CacheOptions
+
+	mu sync.Mutex
+
+	config          *cardConfig
+	configCachedAt  time.Time
+	balance         *cardBalance
+	balanceCachedAt time.Time
+	status          *CardStatus
+	statusCachedAt  time.Time
+
+	now func() time.Time
+}
+
+func NewCachedCard(card CardInterface, options CacheOptions) *CachedCard {
+	if options.ConfigTTL == 0 {
+		options.ConfigTTL = defaultConfigTTL
+	}
+
+	if options.BalanceTTL == 0 {
+		options.BalanceTTL = defaultBalanceTTL
+	}
+
+	return &CachedCard{
+		card:    card,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+func (cached *CachedCard) isFresh(cachedAt time.Time, ttl time.Duration) bool {
+	return !cachedAt.IsZero() && cached.now().Sub(cachedAt) < ttl
+}
+
+// Drops everything that was cached, so the next status will be fetched from the site
+func (cached *CachedCard) Invalidate() {
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	cached.invalidate()
+}
+
+func (cached *CachedCard) invalidate() {
+	cached.config, cached.configCachedAt = nil, time.Time{}
+	cached.balance, cached.balanceCachedAt = nil, time.Time{}
+	cached.status, cached.statusCachedAt = nil, time.Time{}
+}
+
+func (cached *CachedCard) Type() CardType {
+	return cached.card.Type()
+}
+
+func (cached *CachedCard) getCardStatus(ctx context.Context, card *Card, options statusOptions) (*CardStatus, error) {
+	var (
+		config  *cardConfig
+		balance *cardBalance
+	)
+
+	if !options.forceRefresh && cached.isFresh(cached.configCachedAt, cached.options.ConfigTTL) {
+		config = cached.config
+
+		if cached.isFresh(cached.balanceCachedAt, cached.options.BalanceTTL) {
+			balance = cached.balance
+		}
+	}
+
+	if config != nil && balance != nil {
+		return (&cardState{config, balance}).status(), nil
+	}
+
+	state, err := card.fetchState(ctx, "Card.GetStatus", config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		cached.config, cached.configCachedAt = state.config, cached.now()
+	}
+
+	cached.balance, cached.balanceCachedAt = state.balance, cached.now()
+
+	return state.status(), nil
+}
+
+// Gets the status from the cache while it's fresh. Use Invalidate for getting a fresh one.
+func (cached *CachedCard) GetStatus() (*CardStatus, error) {
+	return cached.GetStatusContext(context.Background())
+}
+
+func (cached *CachedCard) GetStatusContext(ctx context.Context) (*CardStatus, error) {
+	// Holding the lock while fetching, so concurrent callers will wait for a single fetch
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if card, ok := cached.card.(*Card); ok {
+		return cached.getCardStatus(ctx, card, statusOptions{})
+	}
+
+	if !cached.isFresh(cached.statusCachedAt, cached.options.BalanceTTL) {
+		status, err := cached.card.GetStatusContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		cached.status, cached.statusCachedAt = status, cached.now()
+	}
+
+	// Callers get their own copy, so they won't mess with the cached one
+	status := *cached.status
+	return &status, nil
+}
+
+func (cached *CachedCard) getCardBalance(ctx context.Context, card *Card, options statusOptions) (*CardBalance, error) {
+	if !options.forceRefresh && cached.isFresh(cached.balanceCachedAt, cached.options.BalanceTTL) {
+		return cached.balance.toCardBalance(), nil
+	}
+
+	config := options.knownLimits
+	if cached.isFresh(cached.configCachedAt, cached.options.ConfigTTL) {
+		config = cached.config
+	}
+
+	// Nothing is known about the limits, so get the complete status and cache it along the way
+	if config == nil {
+		if _, err := cached.getCardStatus(ctx, card, options); err != nil {
+			return nil, err
+		}
+
+		return cached.balance.toCardBalance(), nil
+	}
+
+	state, err := card.fetchState(ctx, "Card.GetBalance", config)
+	if err != nil {
+		return nil, err
+	}
+
+	cached.balance, cached.balanceCachedAt = state.balance, cached.now()
+	return state.balance.toCardBalance(), nil
+}
+
+func (cached *CachedCard) GetBalance(opts ...StatusOption) (*CardBalance, error) {
+	return cached.GetBalanceContext(context.Background(), opts...)
+}
+
+func (cached *CachedCard) GetBalanceContext(ctx context.Context, opts ...StatusOption) (*CardBalance, error) {
+	options := newStatusOptions(opts)
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if card, ok := cached.card.(*Card); ok {
+		return cached.getCardBalance(ctx, card, options)
+	}
+
+	return cached.card.GetBalanceContext(ctx, opts...)
+}
+
+func (cached *CachedCard) GetHistory() (*[]CardHistoryItem, error) {
+	return cached.GetHistoryContext(context.Background())
+}
+
+func (cached *CachedCard) GetHistoryContext(ctx context.Context) (*[]CardHistoryItem, error) {
+	return cached.card.GetHistoryContext(ctx)
+}
+
+func (cached *CachedCard) Load(status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error) {
+	return cached.LoadContext(context.Background(), status, amount, opts...)
+}
+
+// Holding the lock during the load, so statuses fetched while loading won't be cached after the
+// cache is invalidated
+func (cached *CachedCard) LoadContext(ctx context.Context, status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error) {
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	result, err := cached.card.LoadContext(ctx, status, amount, opts...)
+
+	if err == nil && result.Status == StatusSuccess {
+		cached.invalidate()
+	}
+
+	return result, err
+}
+
+func (cached *CachedCard) manage(action func() (*ManageResult, error)) (*ManageResult, error) {
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	result, err := action()
+
+	if err == nil && result.Outcome == OutcomeDone {
+		cached.invalidate()
+	}
+
+	return result, err
+}
+
+func (cached *CachedCard) Block() (*ManageResult, error) {
+	return cached.BlockContext(context.Background())
+}
+
+func (cached *CachedCard) BlockContext(ctx context.Context) (*ManageResult, error) {
+	return cached.manage(func() (*ManageResult, error) { return cached.card.BlockContext(ctx) })
+}
+
+func (cached *CachedCard) Unblock() (*ManageResult, error) {
+	return cached.UnblockContext(context.Background())
+}
+
+func (cached *CachedCard) UnblockContext(ctx context.Context) (*ManageResult, error) {
+	return cached.manage(func() (*ManageResult, error) { return cached.card.UnblockContext(ctx) })
+}
+
+func (cached *CachedCard) RequestReplacement() (*ManageResult, error) {
+	return cached.RequestReplacementContext(context.Background())
+}
+
+func (cached *CachedCard) RequestReplacementContext(ctx context.Context) (*ManageResult, error) {
+	return cached.manage(func() (*ManageResult, error) { return cached.card.RequestReplacementContext(ctx) })
+}
@@ -0,0 +1,368 @@
+package gohever
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+// A card returning a preset status, counting the calls made to it
+type stubCard struct {
+	CardInterface
+
+	status *CardStatus
+	calls  int
+}
+
+func (c *stubCard) GetStatusContext(ctx context.Context) (*CardStatus, error) {
+	c.calls++
+	return c.status, nil
+}
+
+// A stub card whose loads wait to be released, adding to the balance once done
+type loadingCard struct {
+	stubCard
+
+	loading chan struct{}
+	release chan struct{}
+}
+
+func (c *loadingCard) LoadContext(ctx context.Context, status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error) {
+	close(c.loading)
+	<-c.release
+
+	c.status = &CardStatus{CurrentBalance: c.status.CurrentBalance + float64(amount)}
+	return &LoadResult{Status: StatusSuccess}, nil
+}
+
+// Sets up a cached card with a controllable clock
+func setupCachedCard(card CardInterface) (*CachedCard, func(d time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cached := NewCachedCard(card, CacheOptions{
+		ConfigTTL:  time.Hour,
+		BalanceTTL: time.Minute,
+	})
+
+	cached.now = func() time.Time { return now }
+
+	return cached, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCachedCard(t *testing.T) {
+	t.Run("should cache the config and the balance", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		first, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		second, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, 512.0, second.CurrentBalance)
+		assert.Equal(t, 4500, second.MaxMonthlyAmount)
+	})
+
+	t.Run("should only fetch the balance when it expires", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, advance := setupCachedCard(newCard(client, TypeKeva))
+
+		_, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		advance(2 * time.Minute)
+
+		_, err = cached.GetStatus()
+		require.NoError(t, err)
+	})
+
+	t.Run("should fetch everything when the config expires", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, advance := setupCachedCard(newCard(client, TypeKeva))
+
+		_, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		advance(2 * time.Hour)
+
+		_, err = cached.GetStatus()
+		require.NoError(t, err)
+	})
+
+	t.Run("should bypass the cache when forced to", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		_, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		cached.Invalidate()
+
+		_, err = cached.GetStatus()
+		require.NoError(t, err)
+	})
+
+	t.Run("should invalidate the cache after a successful load", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+					Times(2).
+					Status(200).
+					Body(fixtureCardBalance).
+					MatchFormData(testutils.FormData{
+						"balance_only":           "1",
+						"current_max_month_load": "4500",
+						"current_max_load":       "1000",
+					}),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureLoadSuccess),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		status, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		result, err := cached.Load(*status, 100)
+		require.NoError(t, err)
+		assert.Equal(t, StatusSuccess, result.Status)
+
+		_, err = cached.GetStatus()
+		require.NoError(t, err)
+	})
+
+	t.Run("should keep the cache after a failed load", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+					Times(1).
+					Status(200).
+					Body(fixtureCardBalance).
+					MatchFormData(testutils.FormData{
+						"balance_only":           "1",
+						"current_max_month_load": "4500",
+						"current_max_load":       "1000",
+					}),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureLoadDeclined),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		status, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		result, err := cached.Load(*status, 100)
+		require.NoError(t, err)
+		assert.Equal(t, StatusError, result.Status)
+
+		_, err = cached.GetStatus()
+		require.NoError(t, err)
+	})
+
+	t.Run("should not cache statuses fetched during a load", func(t *testing.T) {
+		card := &loadingCard{
+			stubCard: stubCard{status: &CardStatus{CurrentBalance: 100}},
+			loading:  make(chan struct{}),
+			release:  make(chan struct{}),
+		}
+		cached, _ := setupCachedCard(card)
+
+		status, _ := cached.GetStatus()
+
+		go cached.Load(*status, 100)
+		<-card.loading
+
+		fetched := make(chan *CardStatus)
+		go func() {
+			cached.Invalidate()
+
+			status, _ := cached.GetStatus()
+			fetched <- status
+		}()
+
+		select {
+		case <-fetched:
+			require.Fail(t, "the status was fetched during the load")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(card.release)
+		assert.Equal(t, 200.0, (<-fetched).CurrentBalance)
+
+		status, _ = cached.GetStatus()
+		assert.Equal(t, 200.0, status.CurrentBalance)
+	})
+
+	t.Run("should cache the whole status of other cards", func(t *testing.T) {
+		card := &stubCard{status: &CardStatus{CurrentBalance: 100}}
+		cached, advance := setupCachedCard(card)
+
+		status, _ := cached.GetStatus()
+		assert.Equal(t, 100.0, status.CurrentBalance)
+
+		cached.GetStatus()
+		assert.Equal(t, 1, card.calls)
+
+		advance(2 * time.Minute)
+
+		cached.GetStatus()
+		assert.Equal(t, 2, card.calls)
+
+		cached.Invalidate()
+
+		cached.GetStatus()
+		assert.Equal(t, 3, card.calls)
+	})
+}
+
+func TestCachedCardGetBalance(t *testing.T) {
+	t.Run("should use the cached config and balance", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, advance := setupCachedCard(newCard(client, TypeKeva))
+
+		_, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		// Taken from the cache
+		balance, err := cached.GetBalance()
+		require.NoError(t, err)
+		assert.Equal(t, 512.0, balance.CurrentBalance)
+
+		// Only the balance is fetched
+		advance(2 * time.Minute)
+
+		balance, err = cached.GetBalance()
+		require.NoError(t, err)
+		assert.Equal(t, 488.0, balance.RemainingOnCardAmount)
+	})
+
+	t.Run("should only fetch the balance when forced to", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		_, err := cached.GetStatus()
+		require.NoError(t, err)
+
+		balance, err := cached.GetBalance(ForceRefresh())
+		require.NoError(t, err)
+		assert.Equal(t, 512.0, balance.CurrentBalance)
+	})
+
+	t.Run("should get the complete status when nothing is cached", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		balance, err := cached.GetBalance()
+		require.NoError(t, err)
+		assert.Equal(t, 3988.0, balance.RemainingMonthlyAmount)
+
+		// Everything is cached now
+		_, err = cached.GetStatus()
+		require.NoError(t, err)
+	})
+}
+
+func TestCachedCardManage(t *testing.T) {
+	t.Run("should invalidate the cache once the action is done", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks: append(manageMocks("block", fixtureManageConfirm, fixtureManageDone),
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(2).Status(200).Body(fixtureCardBalance),
+			),
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		cached.GetStatus()
+
+		result, err := cached.Block()
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeDone, result.Outcome)
+
+		cached.GetStatus()
+	})
+
+	t.Run("should keep the cache when the action is rejected", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks: []*testutils.MockedRequest{
+				manageRequestMock("unblock", fixtureManageRejected).Once(),
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Times(1).Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		cached, _ := setupCachedCard(newCard(client, TypeKeva))
+
+		cached.GetStatus()
+
+		result, err := cached.Unblock()
+		require.NoError(t, err)
+		assert.Equal(t, OutcomeRejected, result.Outcome)
+
+		cached.GetStatus()
+	})
+}
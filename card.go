@@ -1,24 +1,23 @@
 package gohever
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 var (
-	regexCardFactor1 = regexp.MustCompile("var gift_card_factor1 = ([0-9\\.]+);")
-	regexCardFactor2 = regexp.MustCompile("var gift_card_factor2 = ([0-9\\.]+);")
-	regexCardFactor3 = regexp.MustCompile("var gift_card_factor3 = ([0-9\\.]+);")
-
-	regexCardFactorPrice1 = regexp.MustCompile("var gift_card_factor1_price = ([0-9\\.]+);")
-	regexCardFactorPrice2 = regexp.MustCompile("var gift_card_factor2_price = ([0-9\\.]+);")
-	regexCardFactorPrice3 = regexp.MustCompile("var gift_card_factor3_price = ([0-9\\.]+);")
+	regexDefaultCardFactors = newFactorRegexes(defaultFactorVariable)
 
 	regexMaxMonthLoad = regexp.MustCompile("var max_month_load = ([0-9\\.]+);")
 	regexMaxOnCard    = regexp.MustCompile("var max_on_card = ([0-9\\.]+);")
@@ -27,82 +26,140 @@ var (
 
 	regexLoadStatusCode = regexp.MustCompile("if \\( (\\d) == 1 \\)")
 	regexPlainNumber    = regexp.MustCompile("\\d+")
+
+	// The credit card ("כרטיס האשראי") mentioned by the reason of a declined load
+	regexCreditCardDeclined = regexp.MustCompile("כרטיס ה?אשראי")
+
+	// The date layouts of the history, with and without the time
+	historyDateLayouts = []string{"02/01/2006 15:04:05", "02/01/2006 15:04", "02/01/2006"}
 )
 
+// The methods without a context use context.Background(). The Context variants put the requests
+// under the caller's trace, and stop them once the context is done.
 type CardInterface interface {
 	Type() CardType
 
 	GetStatus() (*CardStatus, error)
+	GetStatusContext(ctx context.Context) (*CardStatus, error)
+	GetBalance(opts ...StatusOption) (*CardBalance, error)
+	GetBalanceContext(ctx context.Context, opts ...StatusOption) (*CardBalance, error)
 	GetHistory() (*[]CardHistoryItem, error)
-	Load(status CardStatus, amount int32) (*LoadResult, error)
+	GetHistoryContext(ctx context.Context) (*[]CardHistoryItem, error)
+	Load(status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error)
+	LoadContext(ctx context.Context, status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error)
+
+	Block() (*ManageResult, error)
+	BlockContext(ctx context.Context) (*ManageResult, error)
+	Unblock() (*ManageResult, error)
+	UnblockContext(ctx context.Context) (*ManageResult, error)
+	RequestReplacement() (*ManageResult, error)
+	RequestReplacementContext(ctx context.Context) (*ManageResult, error)
 }
 
 type Card struct {
 	hvr      *Client
 	cardType CardType
+	product  CardProduct
+
+	regexCardFactors *factorRegexes
+
+	// The last config fetched from the site, used for getting the balance
+	mu          sync.Mutex
+	knownConfig *cardConfig
 }
 
 // Represents a factor in the card, for example 30% for 1000ILS
 type CardFactor struct {
-	Factor float64
-	Amount float64
+	Factor float64 `json:"factor"`
+	Amount float64 `json:"amount"`
 }
 
 // The status of a card at a given time
 type CardStatus struct {
 	// The factors "steps" in the card, ordered accordingly. The "Amount" property means the maximum
 	// amount the factor can take.
-	Factors []CardFactor
+	Factors []CardFactor `json:"factors"`
 
 	// The maximum amount we can load the card monthly
-	MaxMonthlyAmount int
+	MaxMonthlyAmount int `json:"max_monthly_amount"`
 
 	// The maximum amount that the card can hold at a given time
-	MaxOnCardAmount int
+	MaxOnCardAmount int `json:"max_on_card_amount"`
 
 	// The current load on the card
-	CurrentBalance float64
+	CurrentBalance float64 `json:"current_balance"`
 
 	// The remaining load until the end of the month
-	RemainingMonthlyAmount float64
+	RemainingMonthlyAmount float64 `json:"remaining_monthly_amount"`
 
 	// The remaning load until the card will be full
-	RemainingOnCardAmount float64
+	RemainingOnCardAmount float64 `json:"remaining_on_card_amount"`
 
 	// The total monthly usage
-	MonthlyUsage float64
+	MonthlyUsage float64 `json:"monthly_usage"`
 
 	// The balance left from previous month and does not count against the current mothly quota
-	Leftovers float64
+	Leftovers float64 `json:"leftovers"`
 
 	// Serial number (internal, used for charging the card)
-	SerialNumber string
+	SerialNumber string `json:"serial_number"`
+}
+
+// The balance of a card. Getting it is much cheaper than getting the complete CardStatus.
+type CardBalance struct {
+	// The current load on the card
+	CurrentBalance float64 `json:"current_balance"`
+
+	// The remaining load until the end of the month
+	RemainingMonthlyAmount float64 `json:"remaining_monthly_amount"`
+
+	// The remaning load until the card will be full
+	RemainingOnCardAmount float64 `json:"remaining_on_card_amount"`
 }
 
 // The result of a load estimation
 type CardEstimate struct {
 	// The final estimation
-	Total         float64
-	TotalFactored float64
+	Total         float64 `json:"total"`
+	TotalFactored float64 `json:"total_factored"`
 
 	// The amount needed to load in order to reach the desired estimation
-	Required         float64
-	RequiredFactored float64
+	Required         float64 `json:"required"`
+	RequiredFactored float64 `json:"required_factored"`
 
 	// Amount taken from leftovers, does not include the factors
-	Leftovers float64
+	Leftovers float64 `json:"leftovers"`
 
 	// Amount taken from factors. The "Amount" property means the amount taken from the factor
 	// in order to reach the total.
-	Factors []CardFactor
+	Factors []CardFactor `json:"factors"`
 }
 
 type CardHistoryItem struct {
-	Id           string
-	Date         string
-	ActionType   CardAction
-	BusinessName string
-	Amount       float64
+	Id           string     `json:"id"`
+	Date         string     `json:"date"`
+	ActionType   CardAction `json:"action"`
+	BusinessName string     `json:"business_name"`
+	Amount       float64    `json:"amount"`
+}
+
+// Parses the date of the item, shown by the site as "dd/mm/yyyy" with an optional time
+func (item CardHistoryItem) ParseDate(location *time.Location) (time.Time, error) {
+	date := strings.TrimSpace(item.Date)
+
+	for _, layout := range historyDateLayouts {
+		if t, err := time.ParseInLocation(layout, date, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse date %q", date)
+}
+
+// Whether the item has an ID from the site. Items without one are given an ID by their position
+// in the history, which changes as the history grows.
+func (item CardHistoryItem) HasId() bool {
+	return !strings.HasPrefix(item.Id, noIdPrefix)
 }
 
 // Card types
@@ -114,6 +171,19 @@ const (
 	TypeSheli
 )
 
+func (cardType CardType) String() string {
+	switch cardType {
+	case TypeKeva:
+		return "keva"
+	case TypeTeamim:
+		return "teamim"
+	case TypeSheli:
+		return "sheli"
+	}
+
+	return "unknown"
+}
+
 // Card history actions
 type CardAction int
 
@@ -122,6 +192,17 @@ const (
 	ActionPurchase
 )
 
+func (action CardAction) String() string {
+	switch action {
+	case ActionLoad:
+		return "load"
+	case ActionPurchase:
+		return "purchase"
+	}
+
+	return "unknown"
+}
+
 // The status of a card load
 type LoadStatus int
 
@@ -131,10 +212,52 @@ const (
 	StatusSuccess
 )
 
+func (status LoadStatus) String() string {
+	switch status {
+	case StatusNone:
+		return "none"
+	case StatusError:
+		return "error"
+	case StatusSuccess:
+		return "success"
+	}
+
+	return "unknown"
+}
+
 type LoadResult struct {
-	Status     LoadStatus
-	LoadNumber string
-	RawMessage string
+	Status     LoadStatus `json:"status"`
+	LoadNumber string     `json:"load_number"`
+	RawMessage string     `json:"raw_message"`
+
+	// Whether the load failed as the credit card was declined, so it may be retried with another one
+	Declined bool `json:"declined"`
+
+	// The name of the credit card the card was loaded with
+	CreditCard string `json:"credit_card"`
+}
+
+// An option for a single card load
+type LoadOption func(options *loadOptions)
+
+type loadOptions struct {
+	creditCard string
+	fallback   bool
+}
+
+// Load the card using the credit card with the given name, rather than the default one
+func WithCreditCard(name string) LoadOption {
+	return func(options *loadOptions) {
+		options.creditCard = name
+	}
+}
+
+// When the credit card is declined, retry the load with the next configured credit cards. Loads
+// failing for any other reason are not retried, as they may fail the same way with every card.
+func WithCreditCardFallback() LoadOption {
+	return func(options *loadOptions) {
+		options.fallback = true
+	}
 }
 
 // The card config parsed from the site, used internally in this package
@@ -157,16 +280,74 @@ type cardBalance struct {
 	remainingOnCardAmount  float64
 }
 
+// Everything needed in order to build the card status
+type cardState struct {
+	config  *cardConfig
+	balance *cardBalance
+}
+
+// An option for getting the card balance
+type StatusOption func(options *statusOptions)
+
+type statusOptions struct {
+	forceRefresh bool
+	knownLimits  *cardConfig
+}
+
+// Bypass the cached balance and get a fresh one from the site, see CachedCard
+func ForceRefresh() StatusOption {
+	return func(options *statusOptions) {
+		options.forceRefresh = true
+	}
+}
+
+// Use the limits of a previously known status when getting the balance, so the card config won't
+// be fetched
+func WithKnownLimits(status CardStatus) StatusOption {
+	return func(options *statusOptions) {
+		options.knownLimits = &cardConfig{
+			maxMonthLoad: status.MaxMonthlyAmount,
+			maxOnCard:    status.MaxOnCardAmount,
+		}
+	}
+}
+
+func newStatusOptions(opts []StatusOption) statusOptions {
+	var options statusOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
 func newCard(hvr *Client, cardType CardType) *Card {
+	product, err := productOf(cardType)
+	if err != nil {
+		product = CardProduct{Type: cardType, Path: urlGiftCard}
+	}
+
+	return newProductCard(hvr, product)
+}
+
+func newProductCard(hvr *Client, product CardProduct) *Card {
+	regexCardFactors := regexDefaultCardFactors
+	if product.FactorVariable != "" && product.FactorVariable != defaultFactorVariable {
+		regexCardFactors = newFactorRegexes(product.FactorVariable)
+	}
+
 	card := &Card{
 		hvr:      hvr,
-		cardType: cardType,
+		cardType: product.Type,
+		product:  product,
+
+		regexCardFactors: regexCardFactors,
 	}
 
 	return card
 }
 
-func parseGetCardConfigResponse(resp *resty.Response) (*cardConfig, error) {
+func parseGetCardConfigResponse(resp *resty.Response, regexCardFactors *factorRegexes) (*cardConfig, error) {
 	body := string(resp.Body())
 
 	var (
@@ -185,13 +366,13 @@ func parseGetCardConfigResponse(resp *resty.Response) (*cardConfig, error) {
 	)
 
 	scanMap := map[*float64]*regexp.Regexp{
-		&cardFactor1: regexCardFactor1,
-		&cardFactor2: regexCardFactor2,
-		&cardFactor3: regexCardFactor3,
+		&cardFactor1: regexCardFactors.factors[0],
+		&cardFactor2: regexCardFactors.factors[1],
+		&cardFactor3: regexCardFactors.factors[2],
 
-		&cardFactorPrice1: regexCardFactorPrice1,
-		&cardFactorPrice2: regexCardFactorPrice2,
-		&cardFactorPrice3: regexCardFactorPrice3,
+		&cardFactorPrice1: regexCardFactors.prices[0],
+		&cardFactorPrice2: regexCardFactors.prices[1],
+		&cardFactorPrice3: regexCardFactors.prices[2],
 
 		&maxMonthLoad: regexMaxMonthLoad,
 		&maxOnCard:    regexMaxOnCard,
@@ -205,13 +386,18 @@ func parseGetCardConfigResponse(resp *resty.Response) (*cardConfig, error) {
 
 		val, err := strconv.ParseFloat(reg.FindStringSubmatch(body)[1], 64)
 		if err != nil {
-			return nil, err
+			return nil, redactError(err)
 		}
 
 		*ptr = val
 	}
 
-	serialNumber = regexSerialNumber.FindStringSubmatch(body)[1]
+	matches := regexSerialNumber.FindStringSubmatch(body)
+	if matches == nil {
+		return nil, ErrUnableToParseCardConfig
+	}
+
+	serialNumber = matches[1]
 
 	return &cardConfig{
 		cardFactor1,
@@ -233,6 +419,10 @@ func parseGetCardBalanceResponse(resp *resty.Response) (*cardBalance, error) {
 	body := string(resp.Body())
 	parts := strings.Split(body, "|")
 
+	if len(parts) < 3 {
+		return nil, ErrUnableToParseCardBalance
+	}
+
 	var (
 		currentBalance         float64
 		remainingMonthlyAmount float64
@@ -249,7 +439,8 @@ func parseGetCardBalanceResponse(resp *resty.Response) (*cardBalance, error) {
 		part := strings.ReplaceAll(strings.TrimSpace(parts[partIndex]), ",", "")
 		val, err := strconv.ParseFloat(part, 64)
 		if err != nil {
-			return nil, err
+			// The response may be an unexpected page, which might echo the credentials back
+			return nil, redactError(err)
 		}
 
 		*ptr = val
@@ -262,7 +453,7 @@ func parseGetCardBalanceResponse(resp *resty.Response) (*cardBalance, error) {
 	}, nil
 }
 
-func parseGetCardHistoryResponse(resp *resty.Response) (*[]CardHistoryItem, error) {
+func parseGetCardHistoryResponse(resp *resty.Response, selectors Selectors) (*[]CardHistoryItem, error) {
 	doc, err := goquery.NewDocumentFromReader(resp.RawBody())
 	if err != nil {
 		return nil, err
@@ -271,10 +462,10 @@ func parseGetCardHistoryResponse(resp *resty.Response) (*[]CardHistoryItem, erro
 	var history []CardHistoryItem
 
 	// Populate formData
-	doc.Find("tr.historyRows[id]").Each(func(i int, s *goquery.Selection) {
+	doc.Find(selectors.HistoryRows).Each(func(i int, s *goquery.Selection) {
 		var item CardHistoryItem
 
-		item.Id = s.AttrOr("id", "no_id_"+strconv.Itoa(i))
+		item.Id = s.AttrOr("id", noIdPrefix+strconv.Itoa(i))
 		item.Date = s.Find("td:nth-child(1)").Text()
 
 		item.ActionType = ActionLoad
@@ -296,7 +487,7 @@ func parseGetCardHistoryResponse(resp *resty.Response) (*[]CardHistoryItem, erro
 	return &history, nil
 }
 
-func parseLoadCardResponse(resp *resty.Response) (*LoadResult, error) {
+func parseLoadCardResponse(resp *resty.Response, selectors Selectors) (*LoadResult, error) {
 	body := string(resp.Body())
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
@@ -316,7 +507,7 @@ func parseLoadCardResponse(resp *resty.Response) (*LoadResult, error) {
 		return &LoadResult{
 			Status:     StatusError,
 			LoadNumber: "",
-			RawMessage: strings.TrimSpace(doc.Find("table.table[bgcolor=red]").Text()),
+			RawMessage: strings.TrimSpace(doc.Find(selectors.LoadError).Text()),
 		}, nil
 	}
 
@@ -332,71 +523,91 @@ func parseLoadCardResponse(resp *resty.Response) (*LoadResult, error) {
 		status = StatusSuccess
 	}
 
-	rawMessage = strings.TrimSpace(doc.Find("div#msg_ok").Text())
+	rawMessage = strings.TrimSpace(doc.Find(selectors.LoadMessage).Text())
 	loadNumber = regexPlainNumber.FindString(rawMessage)
 
+	// Failed loads show their reason in the error table. Only a reason mentioning the credit card
+	// tells a decline apart from the other failures, e.g. of the limits.
+	var declined bool
+	if rejection := doc.Find(selectors.LoadError); status == StatusError && rejection.Length() > 0 {
+		rawMessage = strings.TrimSpace(rejection.Text())
+		loadNumber = ""
+		declined = regexCreditCardDeclined.MatchString(rawMessage)
+	}
+
 	return &LoadResult{
 		Status:     status,
 		LoadNumber: loadNumber,
 		RawMessage: rawMessage,
+		Declined:   declined,
 	}, nil
 }
 
 func (card *Card) buildBaseRequest() *resty.Request {
-	req := card.hvr.newRequest()
-
-	if card.cardType == TypeTeamim {
-		req.SetQueryParam(queryParamFoodCard, "1")
-	}
-
-	return req
+	return card.hvr.newRequest().
+		SetQueryParams(card.product.QueryParams)
 }
 
-func (card *Card) getCardConfig() (*cardConfig, error) {
-	resp, err := card.buildBaseRequest().Get(urlCardConfig)
+func (card *Card) getCardConfig(ctx context.Context) (*cardConfig, error) {
+	resp, err := card.hvr.execute(ctx, endpointCardConfig,
+		card.buildBaseRequest(),
+		resty.MethodGet, card.product.Path)
+
+	if err != nil {
+		return nil, err
+	}
 
+	config, err := parseGetCardConfigResponse(resp, card.regexCardFactors)
 	if err != nil {
+		card.hvr.logParseFailure(ctx, endpointCardConfig, err)
 		return nil, err
 	}
 
-	return parseGetCardConfigResponse(resp)
+	return config, nil
 }
 
-func (card *Card) getCardBalance(config *cardConfig) (*cardBalance, error) {
-	resp, err := card.buildBaseRequest().
+func (card *Card) getCardBalance(ctx context.Context, config *cardConfig) (*cardBalance, error) {
+	req := card.buildBaseRequest().
 		SetFormData(formData{
 			"balance_only":           "1",
 			"current_max_month_load": strconv.Itoa(config.maxMonthLoad),
 			"current_max_load":       strconv.Itoa(config.maxOnCard),
-		}).
-		Post(urlCardStatus)
+		})
 
+	resp, err := card.hvr.execute(ctx, endpointCardBalance, req, resty.MethodPost, card.product.Path)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseGetCardBalanceResponse(resp)
+	balance, err := parseGetCardBalanceResponse(resp)
+	if err != nil {
+		card.hvr.logParseFailure(ctx, endpointCardBalance, err)
+		return nil, err
+	}
+
+	return balance, nil
 }
 
-func (card *Card) getCardHistory() (*[]CardHistoryItem, error) {
-	resp, err := card.buildBaseRequest().
-		SetDoNotParseResponse(true).
-		Get(urlCardHistory)
+func (card *Card) getCardHistory(ctx context.Context) (*[]CardHistoryItem, error) {
+	resp, err := card.hvr.execute(ctx, endpointCardHistory,
+		card.buildBaseRequest().SetDoNotParseResponse(true),
+		resty.MethodGet, card.product.Path)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return parseGetCardHistoryResponse(resp)
-}
-
-func (card *Card) loadCard(status CardStatus, amount int32) (*LoadResult, error) {
-	creditCard, err := card.hvr.config.CreditCard()
+	history, err := parseGetCardHistoryResponse(resp, card.hvr.selectors)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get credit card details from config: %w", err)
+		card.hvr.logParseFailure(ctx, endpointCardHistory, err)
+		return nil, err
 	}
 
-	resp, err := card.buildBaseRequest().
+	return history, nil
+}
+
+func (card *Card) loadCard(ctx context.Context, status CardStatus, amount int32, creditCard CreditCard) (*LoadResult, error) {
+	req := card.buildBaseRequest().
 		SetFormData(formData{
 			"price":      strconv.Itoa(int(amount)),
 			"card_num":   creditCard.Number,
@@ -408,68 +619,236 @@ func (card *Card) loadCard(status CardStatus, amount int32) (*LoadResult, error)
 			"req_sent":   "1",
 
 			"sn": status.SerialNumber,
-		}).
-		Post(urlLoadCard)
+		})
 
+	resp, err := card.hvr.execute(ctx, endpointLoadCard, req, resty.MethodPost, card.product.Path)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseLoadCardResponse(resp)
+	result, err := parseLoadCardResponse(resp, card.hvr.selectors)
+	if err != nil {
+		card.hvr.logParseFailure(ctx, endpointLoadCard, err)
+		return nil, err
+	}
+
+	card.hvr.telemetry.loads.Add(ctx, 1, metric.WithAttributes(
+		attribute.Stringer("gohever.card.type", card.cardType),
+		attribute.Stringer("gohever.load.status", result.Status),
+	))
+
+	result.CreditCard = creditCard.Name
+	return result, nil
 }
 
 func (card *Card) Type() CardType {
 	return card.cardType
 }
 
-func (card *Card) GetStatus() (*CardStatus, error) {
-	return wrapAuthenticated(card.hvr, func() (*CardStatus, error) {
-		config, err := card.getCardConfig()
+// The product the card was created for
+func (card *Card) Product() CardProduct {
+	return card.product
+}
+
+// Fetches the card config and balance. When a known config is given, only the balance is fetched.
+func (card *Card) fetchState(ctx context.Context, operation string, config *cardConfig) (state *cardState, err error) {
+	ctx, span := card.hvr.telemetry.startSpan(ctx, operation,
+		attribute.Stringer("gohever.card.type", card.cardType),
+		attribute.Bool("gohever.card.config_cached", config != nil))
+	defer func() { endSpan(span, err) }()
+
+	return wrapAuthenticated(ctx, card.hvr, func() (*cardState, error) {
+		state := &cardState{config: config}
+
+		if state.config == nil {
+			config, err := card.getCardConfig(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			state.config = config
+
+			card.mu.Lock()
+			card.knownConfig = config
+			card.mu.Unlock()
+		}
+
+		balance, err := card.getCardBalance(ctx, state.config)
 		if err != nil {
 			return nil, err
 		}
 
-		balance, err := card.getCardBalance(config)
+		state.balance = balance
+		return state, nil
+	})()
+}
+
+// Fetches only the card config, remembering it for getting the balance later on
+func (card *Card) fetchConfig(ctx context.Context) (*cardConfig, error) {
+	return wrapAuthenticated(ctx, card.hvr, func() (*cardConfig, error) {
+		config, err := card.getCardConfig(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		monthlyUsage := 1 - (balance.remainingMonthlyAmount / float64(config.maxMonthLoad))
-		leftovers := math.Max(0,
-			balance.currentBalance-monthlyUsage*balance.remainingMonthlyAmount)
+		card.mu.Lock()
+		card.knownConfig = config
+		card.mu.Unlock()
 
-		return &CardStatus{
-			Factors: []CardFactor{
-				{Factor: config.cardFactor1, Amount: config.cardFactorPrice1},
-				{Factor: config.cardFactor2, Amount: config.cardFactorPrice2},
-				{Factor: config.cardFactor3, Amount: config.cardFactorPrice3},
-			},
+		return config, nil
+	})()
+}
 
-			MaxMonthlyAmount: config.maxMonthLoad,
-			MaxOnCardAmount:  config.maxOnCard,
+func (card *Card) getStatus(ctx context.Context) (*CardStatus, error) {
+	state, err := card.fetchState(ctx, "Card.GetStatus", nil)
+	if err != nil {
+		return nil, err
+	}
 
-			CurrentBalance:         balance.currentBalance,
-			RemainingMonthlyAmount: balance.remainingMonthlyAmount,
-			RemainingOnCardAmount:  balance.remainingOnCardAmount,
+	return state.status(), nil
+}
 
-			MonthlyUsage: monthlyUsage,
-			Leftovers:    leftovers,
+func (card *Card) getBalance(ctx context.Context, options statusOptions) (*CardBalance, error) {
+	config := options.knownLimits
 
-			SerialNumber: config.serialNumber,
-		}, nil
+	if config == nil {
+		card.mu.Lock()
+		config = card.knownConfig
+		card.mu.Unlock()
+	}
+
+	// With no known limits, the config will be fetched as well
+	state, err := card.fetchState(ctx, "Card.GetBalance", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.balance.toCardBalance(), nil
+}
+
+// Gets the card balance using a single small request. The limits needed for the request are taken
+// from WithKnownLimits, or from the last time the card config was fetched. When none are known,
+// the card config is fetched first.
+func (card *Card) GetBalance(opts ...StatusOption) (*CardBalance, error) {
+	return card.GetBalanceContext(context.Background(), opts...)
+}
+
+func (card *Card) GetBalanceContext(ctx context.Context, opts ...StatusOption) (*CardBalance, error) {
+	return card.getBalance(ctx, newStatusOptions(opts))
+}
+
+// Cards always get a fresh status from the site. See CachedCard for caching.
+func (card *Card) GetStatus() (*CardStatus, error) {
+	return card.GetStatusContext(context.Background())
+}
+
+func (card *Card) GetStatusContext(ctx context.Context) (*CardStatus, error) {
+	return card.getStatus(ctx)
+}
+
+func (card *Card) getHistory(ctx context.Context) (history *[]CardHistoryItem, err error) {
+	ctx, span := card.hvr.telemetry.startSpan(ctx, "Card.GetHistory",
+		attribute.Stringer("gohever.card.type", card.cardType))
+	defer func() { endSpan(span, err) }()
+
+	return wrapAuthenticated(ctx, card.hvr, func() (*[]CardHistoryItem, error) {
+		return card.getCardHistory(ctx)
 	})()
 }
 
 func (card *Card) GetHistory() (*[]CardHistoryItem, error) {
-	return wrapAuthenticated(card.hvr, func() (*[]CardHistoryItem, error) {
-		return card.getCardHistory()
-	})()
+	return card.GetHistoryContext(context.Background())
 }
 
-func (card *Card) Load(status CardStatus, amount int32) (*LoadResult, error) {
-	return wrapAuthenticated(card.hvr, func() (*LoadResult, error) {
-		return card.loadCard(status, amount)
-	})()
+func (card *Card) GetHistoryContext(ctx context.Context) (*[]CardHistoryItem, error) {
+	return card.getHistory(ctx)
+}
+
+func (card *Card) load(ctx context.Context, status CardStatus, amount int32, opts ...LoadOption) (result *LoadResult, err error) {
+	ctx, span := card.hvr.telemetry.startSpan(ctx, "Card.Load",
+		attribute.Stringer("gohever.card.type", card.cardType),
+		attribute.Int("gohever.load.amount", int(amount)))
+	defer func() { endSpan(span, err) }()
+
+	var options loadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	creditCards, err := card.hvr.config.creditCards()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get credit card details from config: %w", err)
+	}
+
+	creditCards, err = selectCreditCard(creditCards, options.creditCard)
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.fallback {
+		creditCards = creditCards[:1]
+	}
+
+	// Try the credit cards in order, moving to the next one only when the credit card was declined
+	for _, creditCard := range creditCards {
+		result, err = wrapAuthenticated(ctx, card.hvr, func() (*LoadResult, error) {
+			return card.loadCard(ctx, status, amount, creditCard)
+		})()
+
+		if err != nil || !result.Declined {
+			break
+		}
+	}
+
+	if result != nil {
+		span.SetAttributes(attribute.Stringer("gohever.load.status", result.Status))
+	}
+
+	return result, err
+}
+
+func (card *Card) Load(status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error) {
+	return card.LoadContext(context.Background(), status, amount, opts...)
+}
+
+func (card *Card) LoadContext(ctx context.Context, status CardStatus, amount int32, opts ...LoadOption) (*LoadResult, error) {
+	return card.load(ctx, status, amount, opts...)
+}
+
+func (balance *cardBalance) toCardBalance() *CardBalance {
+	return &CardBalance{
+		CurrentBalance:         balance.currentBalance,
+		RemainingMonthlyAmount: balance.remainingMonthlyAmount,
+		RemainingOnCardAmount:  balance.remainingOnCardAmount,
+	}
+}
+
+func (state *cardState) status() *CardStatus {
+	config, balance := state.config, state.balance
+
+	monthlyUsage := 1 - (balance.remainingMonthlyAmount / float64(config.maxMonthLoad))
+	leftovers := math.Max(0,
+		balance.currentBalance-monthlyUsage*balance.remainingMonthlyAmount)
+
+	return &CardStatus{
+		Factors: []CardFactor{
+			{Factor: config.cardFactor1, Amount: config.cardFactorPrice1},
+			{Factor: config.cardFactor2, Amount: config.cardFactorPrice2},
+			{Factor: config.cardFactor3, Amount: config.cardFactorPrice3},
+		},
+
+		MaxMonthlyAmount: config.maxMonthLoad,
+		MaxOnCardAmount:  config.maxOnCard,
+
+		CurrentBalance:         balance.currentBalance,
+		RemainingMonthlyAmount: balance.remainingMonthlyAmount,
+		RemainingOnCardAmount:  balance.remainingOnCardAmount,
+
+		MonthlyUsage: monthlyUsage,
+		Leftovers:    leftovers,
+
+		SerialNumber: config.serialNumber,
+	}
 }
 
 func (status *CardStatus) Estimate(amount float64) (*CardEstimate, error) {
@@ -1,8 +1,11 @@
 package gohever
 
 import (
+	"context"
 	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yardnsm/gohever/testutils"
@@ -55,6 +58,74 @@ func TestCardBaseConfig(t *testing.T) {
 	})
 }
 
+// A product served from its own orders page, with its own factor variables
+var testProductVoucher = CardProduct{
+	Type:           TypeSheli,
+	Path:           "orders/voucher.aspx",
+	FactorVariable: "voucher_factor",
+}
+
+func TestCardProducts(t *testing.T) {
+	t.Run("product with its own factor variables", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/voucher.aspx").Once().Status(200).Body(fixtureVoucherConfig),
+				testutils.NewMockedRequest("POST", "/orders/voucher.aspx").Once().Status(200).Body("250 | 750 | 250"),
+			},
+		})
+
+		card := newProductCard(client, testProductVoucher)
+		assert.Equal(t, testProductVoucher, card.Product())
+
+		status, err := card.GetStatus()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []CardFactor{
+			{Factor: 0.85, Amount: 500},
+			{Factor: 0.9, Amount: 500},
+			{Factor: 0.95, Amount: 1000},
+		}, status.Factors)
+		assert.Equal(t, 2000, status.MaxMonthlyAmount)
+		assert.Equal(t, 250.0, status.CurrentBalance)
+		assert.Equal(t, "87654321-9abc-def1-2345-6789abcdef12", status.SerialNumber)
+	})
+
+	t.Run("product does not parse the gift card variables", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/voucher.aspx").Once().Status(200).Body(fixtureCardConfig),
+			},
+		})
+
+		_, err := newProductCard(client, testProductVoucher).GetStatus()
+
+		assert.ErrorIs(t, err, ErrUnableToParseCardConfig)
+	})
+
+	t.Run("custom product", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/voucher.aspx?partner=7").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/voucher.aspx?partner=7").Once().Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		card := newProductCard(client, CardProduct{
+			Type:        TypeKeva,
+			Path:        "orders/voucher.aspx",
+			QueryParams: map[string]string{"partner": "7"},
+		})
+
+		status, err := card.GetStatus()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 512.0, status.CurrentBalance)
+	})
+}
+
 func TestGetCardConfig(t *testing.T) {
 	client := SetupTestClient(t, TestClientConfig{
 		Mocks: []*testutils.MockedRequest{
@@ -64,7 +135,7 @@ func TestGetCardConfig(t *testing.T) {
 
 	card := newCard(client, TypeKeva)
 
-	config, _ := card.getCardConfig()
+	config, _ := card.getCardConfig(context.Background())
 
 	assert.Equal(t, &cardConfig{
 		cardFactor1:      0.7,
@@ -98,7 +169,7 @@ func TestCardGetBalance(t *testing.T) {
 
 	card := newCard(client, TypeKeva)
 
-	balance, err := card.getCardBalance(&cardConfig{
+	balance, err := card.getCardBalance(context.Background(), &cardConfig{
 		cardFactor1:      0.7,
 		cardFactor2:      0.8,
 		cardFactor3:      0.9,
@@ -369,3 +440,243 @@ func TestCardLoad(t *testing.T) {
 		}, result)
 	})
 }
+
+func TestCardLoadCreditCards(t *testing.T) {
+	creditCards := BasicCreditCards(
+		CreditCard{Name: "personal", Number: "4580111122223333", Month: "01", Year: "2030"},
+		CreditCard{Name: "work", Number: "4580444455556666", Month: "02", Year: "2031"},
+	)
+
+	loadFormData := func(number, month, year string) testutils.FormData {
+		return testutils.FormData{
+			"price":      "500",
+			"card_num":   number,
+			"card_year":  year,
+			"card_month": month,
+
+			"chkTakanon": "",
+			"om":         "load",
+			"req_sent":   "1",
+
+			"sn": "12345678-9abc-def1-2345-6789abcdef12",
+		}
+	}
+
+	setupTest := func(t *testing.T, mocks ...*testutils.MockedRequest) *Card {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks:         mocks,
+		})
+
+		client.config.CreditCards = creditCards
+
+		return newCard(client, TypeKeva)
+	}
+
+	t.Run("should use the first credit card by default", func(t *testing.T) {
+		card := setupTest(t,
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+				Once().
+				Status(200).
+				Body(fixtureLoadSuccess).
+				MatchFormData(loadFormData("4580111122223333", "01", "2030")),
+		)
+
+		result, err := card.Load(setupCardStatus(400, 0, 0), 500)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, result.Status)
+		assert.Equal(t, "personal", result.CreditCard)
+	})
+
+	t.Run("should use the selected credit card", func(t *testing.T) {
+		card := setupTest(t,
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+				Once().
+				Status(200).
+				Body(fixtureLoadSuccess).
+				MatchFormData(loadFormData("4580444455556666", "02", "2031")),
+		)
+
+		result, err := card.Load(setupCardStatus(400, 0, 0), 500, WithCreditCard("work"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, result.Status)
+		assert.Equal(t, "work", result.CreditCard)
+	})
+
+	t.Run("should fail when the selected credit card does not exist", func(t *testing.T) {
+		card := setupTest(t)
+
+		_, err := card.Load(setupCardStatus(400, 0, 0), 500, WithCreditCard("other"))
+
+		assert.ErrorIs(t, err, ErrCreditCardNotFound)
+	})
+
+	t.Run("should not fallback when not asked to", func(t *testing.T) {
+		card := setupTest(t,
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+				Once().
+				Status(200).
+				Body(fixtureLoadDeclined).
+				MatchFormData(loadFormData("4580111122223333", "01", "2030")),
+		)
+
+		result, err := card.Load(setupCardStatus(400, 0, 0), 500)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusError, result.Status)
+		assert.True(t, result.Declined)
+		assert.Equal(t, "כרטיס האשראי נדחה", result.RawMessage)
+		assert.Equal(t, "personal", result.CreditCard)
+	})
+
+	t.Run("should fallback to the next credit card when declined", func(t *testing.T) {
+		card := setupTest(t,
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+				Once().
+				Status(200).
+				Body(fixtureLoadDeclined).
+				MatchFormData(loadFormData("4580444455556666", "02", "2031")),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+				Once().
+				Status(200).
+				Body(fixtureLoadSuccess).
+				MatchFormData(loadFormData("4580111122223333", "01", "2030")),
+		)
+
+		result, err := card.Load(setupCardStatus(400, 0, 0), 500,
+			WithCreditCard("work"),
+			WithCreditCardFallback(),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, result.Status)
+		assert.Equal(t, "12344321", result.LoadNumber)
+		assert.Equal(t, "personal", result.CreditCard)
+	})
+
+	t.Run("should only fallback when the credit card was declined", func(t *testing.T) {
+		// The reason alone, without the status code of the load, isn't enough
+		declinedWithoutStatus := strings.Replace(fixtureLoadDeclined, "<script>if ( 1 == 1 ) { show_msg(); }</script>", "", 1)
+
+		for _, body := range []string{fixtureLoadAboveLimit, declinedWithoutStatus, "<html></html>"} {
+			card := setupTest(t,
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+					Once().
+					Status(200).
+					Body(body).
+					MatchFormData(loadFormData("4580444455556666", "02", "2031")),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+					Status(200).
+					Body(fixtureLoadSuccess).
+					MatchFormData(loadFormData("4580111122223333", "01", "2030")).
+					ExpectNot(),
+			)
+
+			result, err := card.Load(setupCardStatus(400, 0, 0), 500,
+				WithCreditCard("work"),
+				WithCreditCardFallback(),
+			)
+
+			assert.NoError(t, err)
+			assert.Equal(t, StatusError, result.Status)
+			assert.False(t, result.Declined)
+			assert.Equal(t, "work", result.CreditCard)
+		}
+	})
+}
+
+func TestCardGetBalanceOnly(t *testing.T) {
+	balanceMock := func() *testutils.MockedRequest {
+		return testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+			Status(200).
+			Body(fixtureCardBalance).
+			MatchFormData(testutils.FormData{
+				"balance_only":           "1",
+				"current_max_month_load": "4500",
+				"current_max_load":       "1000",
+			})
+	}
+
+	expected := &CardBalance{
+		CurrentBalance:         512,
+		RemainingMonthlyAmount: 3988,
+		RemainingOnCardAmount:  488,
+	}
+
+	t.Run("should use the limits of a known status", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).ExpectNot(),
+				balanceMock().Once(),
+			},
+		})
+
+		card := newCard(client, TypeKeva)
+
+		balance, err := card.GetBalance(WithKnownLimits(CardStatus{
+			MaxMonthlyAmount: 4500,
+			MaxOnCardAmount:  1000,
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, balance)
+	})
+
+	t.Run("should fetch the config only once", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				balanceMock().Times(2),
+			},
+		})
+
+		card := newCard(client, TypeKeva)
+
+		balance, err := card.GetBalance()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, balance)
+
+		balance, err = card.GetBalance()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, balance)
+	})
+
+	t.Run("should reuse the limits from a previous status", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				balanceMock().Times(2),
+			},
+		})
+
+		card := newCard(client, TypeKeva)
+
+		_, err := card.GetStatus()
+		assert.NoError(t, err)
+
+		balance, err := card.GetBalance()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, balance)
+	})
+}
+
+func TestCardHistoryItemParseDate(t *testing.T) {
+	date, err := CardHistoryItem{Date: " 14/03/2024 "}.ParseDate(time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), date)
+
+	date, err = CardHistoryItem{Date: "14/03/2024 12:30"}.ParseDate(time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 14, 12, 30, 0, 0, time.UTC), date)
+
+	_, err = CardHistoryItem{Date: "2024-03-14"}.ParseDate(time.UTC)
+	assert.Error(t, err)
+
+	assert.True(t, CardHistoryItem{Id: "1234"}.HasId())
+	assert.False(t, CardHistoryItem{Id: "no_id_3"}.HasId())
+}
@@ -0,0 +1,126 @@
+package gohever
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+func setupCassetteClient(t *testing.T, server *testutils.MockServer, initResty func(r *resty.Client)) *Client {
+	return NewClient(FlavorHvr(), Config{
+		Credentials: BasicCredentials("TestUsername", "TestPassword"),
+		CreditCard:  BasicCreditCard("45801234567899012", "04", "2023"),
+		BaseURL:     server.URL(),
+		InitResty:   initResty,
+	})
+}
+
+func TestCassette(t *testing.T) {
+	server := testutils.NewMockServer().SetupTest(t)
+	server.
+		Mock(testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthConfig)).
+		Mock(testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Once().Status(200)).
+		Mock(testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthSuccessful)).
+		Mock(testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig)).
+		Mock(testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardBalance)).
+		Mock(testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureLoadSuccess))
+
+	recorder := testutils.NewRecorder(nil, testutils.Scrubber{
+		Secrets: []string{"TestUsername"},
+	})
+
+	client := setupCassetteClient(t, server, func(r *resty.Client) {
+		r.SetTransport(recorder)
+	})
+
+	status, err := newCard(client, TypeKeva).GetStatus()
+	assert.NoError(t, err)
+
+	result, err := newCard(client, TypeKeva).Load(*status, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSuccess, result.Status)
+
+	fileName := filepath.Join(t.TempDir(), "card_load.json")
+	assert.NoError(t, recorder.Save(fileName))
+
+	t.Run("should scrub the recorded session", func(t *testing.T) {
+		cassette, err := testutils.LoadCassette(fileName)
+		assert.NoError(t, err)
+		assert.Len(t, cassette.Interactions, 6)
+
+		data, _ := json.Marshal(cassette)
+		for _, secret := range []string{"TestUsername", "TestPassword", "45801234567899012", status.SerialNumber, "ישראלי"} {
+			assert.NotContains(t, string(data), secret)
+		}
+
+		load := cassette.Interactions[5].Request
+		assert.Equal(t, testutils.Scrubbed, load.Form["card_num"])
+		assert.Equal(t, "100", load.Form["price"])
+	})
+
+	t.Run("should replay the recorded session", func(t *testing.T) {
+		cassette, err := testutils.LoadCassette(fileName)
+		assert.NoError(t, err)
+
+		replay := testutils.NewMockServer().SetupTest(t).Replay(cassette)
+		client := setupCassetteClient(t, replay, nil)
+
+		replayedStatus, err := newCard(client, TypeKeva).GetStatus()
+		assert.NoError(t, err)
+		assert.Equal(t, status.CurrentBalance, replayedStatus.CurrentBalance)
+		assert.Equal(t, status.Factors, replayedStatus.Factors)
+
+		replayedResult, err := newCard(client, TypeKeva).Load(*replayedStatus, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, result.LoadNumber, replayedResult.LoadNumber)
+	})
+
+	t.Run("should not replay requests with different form data", func(t *testing.T) {
+		cassette, err := testutils.LoadCassette(fileName)
+		assert.NoError(t, err)
+
+		// Leave out the load, so an unmatched request fails instead of the whole test
+		cassette.Interactions = cassette.Interactions[:5]
+
+		replay := testutils.NewMockServer().SetupTest(t).Replay(cassette)
+		client := setupCassetteClient(t, replay, nil)
+
+		_, err = newCard(client, TypeKeva).GetBalance(WithKnownLimits(CardStatus{
+			MaxMonthlyAmount: 1,
+			MaxOnCardAmount:  1,
+		}))
+		assert.Error(t, err)
+
+		// Consume the rest of the session, as the replay expects all of it
+		_, err = newCard(client, TypeKeva).GetStatus()
+		assert.NoError(t, err)
+	})
+}
+
+func TestScrubber(t *testing.T) {
+	scrubber := testutils.Scrubber{}
+
+	t.Run("should scrub the holder name and the greeting by default", func(t *testing.T) {
+		page := `<div class="user">שלום ישראל ישראלי</div><span id="card_holder" class="name">ישראל ישראלי</span>`
+
+		assert.Equal(t,
+			`<div class="user">שלום SCRUBBED</div><span id="card_holder" class="name">SCRUBBED</span>`,
+			scrubber.Scrub(page))
+	})
+
+	t.Run("should not scrub words ending like a greeting", func(t *testing.T) {
+		assert.Equal(t, "<b>תשלום מאובטח</b>", scrubber.Scrub("<b>תשלום מאובטח</b>"))
+	})
+
+	t.Run("should scrub the given elements instead of the default ones", func(t *testing.T) {
+		scrubber := testutils.Scrubber{Elements: []string{"owner"}}
+
+		assert.Equal(t,
+			`<p id="owner">SCRUBBED</p><p id="card_holder">ישראל</p>`,
+			scrubber.Scrub(`<p id="owner">ישראל</p><p id="card_holder">ישראל</p>`))
+	})
+}
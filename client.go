@@ -1,40 +1,75 @@
 package gohever
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type siteFlavor int
+type Client struct {
+	flavor    *Flavor
+	selectors Selectors
+	config    Config
 
-const (
-	FlavorHvr siteFlavor = iota
-	FlavorMcc
-)
+	// All of the site's paths are resolved against the base URL. When the flavor or the base URL
+	// are invalid, every request fails with configErr instead.
+	baseURL   *url.URL
+	configErr error
 
-type Client struct {
-	flavor siteFlavor
-	config Config
-	r      *resty.Client
+	r         *resty.Client
+	logger    *slog.Logger
+	telemetry *telemetry
 
+	// Guards the authentication state. Logins are serialized, so concurrent requests won't
+	// login more than once.
+	mu              sync.RWMutex
+	loginMu         sync.Mutex
 	isAuthenticated bool
+	sessionNumber   int
 
-	Auth  AuthInterface
-	Cards struct {
-		Keva   CardInterface
-		Teamim CardInterface
-		Sheli  CardInterface
-	}
+	Auth AuthInterface
+
+	// The cards available for the flavor, in a stable order
+	cardsMu sync.RWMutex
+	cards   []CardInterface
+}
+
+// The status of a card, or the error getting it
+type CardStatusResult struct {
+	Status *CardStatus
+	Err    error
 }
 
-func NewClient(flavor siteFlavor, config Config) *Client {
+// Creates a client for the given flavor, which is copied. A nil flavor fails every request with
+// ErrInvalidFlavor.
+func NewClient(flavor *Flavor, config Config) *Client {
+	var flavorErr error
+	if flavor == nil {
+		flavor, flavorErr = &Flavor{}, ErrInvalidFlavor
+	} else {
+		flavor = flavor.clone()
+	}
+
 	r := resty.New()
 
 	client := &Client{
-		flavor: flavor,
-		config: config,
-		r:      r,
+		flavor:    flavor,
+		configErr: flavorErr,
+		selectors: flavor.selectors(),
+		config:    config,
+		r:         r,
+		logger:    newLogger(config.Logger),
+		telemetry: newTelemetry(config.TracerProvider, config.MeterProvider),
 
 		isAuthenticated: false,
 	}
@@ -44,28 +79,39 @@ func NewClient(flavor siteFlavor, config Config) *Client {
 	return client
 }
 
+// Returns a copy of the client's flavor
+func (hvr *Client) Flavor() *Flavor {
+	return hvr.flavor.clone()
+}
+
 func (hvr *Client) init() {
 
 	// Setup endpoints
 	hvr.Auth = newAuth(hvr)
 
 	// Setup Cards
-	switch hvr.flavor {
-	case FlavorHvr:
-		hvr.Cards.Keva = newCard(hvr, TypeKeva)
-		hvr.Cards.Teamim = newCard(hvr, TypeTeamim)
+	for _, product := range hvr.flavor.Products {
+		hvr.RegisterCard(newProductCard(hvr, product))
+	}
 
-	case FlavorMcc:
-		hvr.Cards.Sheli = newCard(hvr, TypeSheli)
+	baseURL := hvr.flavor.BaseURL
+	if hvr.config.BaseURL != "" {
+		baseURL = hvr.config.BaseURL
 	}
 
-	baseUrl := heverBaseUrl
-	if hvr.flavor == FlavorMcc {
-		baseUrl = mccBaseUrl
+	if hvr.configErr == nil {
+		hvr.baseURL, hvr.configErr = parseBaseURL(baseURL)
+	}
+
+	if hvr.configErr == nil {
+		hvr.r.SetBaseURL(hvr.baseURL.String())
 	}
 
 	// Setup resty
-	hvr.r.SetBaseURL(baseUrl)
+	if hvr.config.Proxy != "" {
+		hvr.r.SetProxy(hvr.config.Proxy)
+	}
+
 	hvr.r.SetRedirectPolicy(
 		resty.RedirectPolicyFunc(hvr.redirectPolicy),
 	)
@@ -74,33 +120,326 @@ func (hvr *Client) init() {
 	hvr.r.SetHeader("User-Agent", heverUserAgent)
 	hvr.r.SetHeader("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9")
 
+	// Keep credentials and credit cards out of the debug logs
+	hvr.r.OnRequestLog(redactRequestLog)
+	hvr.r.OnResponseLog(redactResponseLog)
+
 	if hvr.config.InitResty != nil {
 		hvr.config.InitResty(hvr.r)
 	}
 }
 
+func parseBaseURL(rawURL string) (*url.URL, error) {
+	baseURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBaseURL, err)
+	}
+
+	if !baseURL.IsAbs() {
+		return nil, fmt.Errorf("%w: %q is not absolute", ErrInvalidBaseURL, rawURL)
+	}
+
+	// Paths are relative to the base URL, so it should be treated as a directory
+	if !strings.HasSuffix(baseURL.Path, "/") {
+		baseURL.Path += "/"
+	}
+
+	return baseURL, nil
+}
+
+// Resolves a path of the site against the base URL. Absolute URLs are kept as is.
+func (hvr *Client) resolve(path string) (*url.URL, error) {
+	if hvr.configErr != nil {
+		return nil, hvr.configErr
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return hvr.baseURL.ResolveReference(ref), nil
+}
+
+// Returns whether the request URL points to the given path of the site, ignoring the query
+func (hvr *Client) isPath(requestURL *url.URL, path string) bool {
+	resolved, err := hvr.resolve(path)
+	if err != nil {
+		return false
+	}
+
+	return requestURL.Path == resolved.Path
+}
+
+func (hvr *Client) authenticated() bool {
+	hvr.mu.RLock()
+	defer hvr.mu.RUnlock()
+
+	return hvr.isAuthenticated
+}
+
+func (hvr *Client) setAuthenticated(authenticated bool) {
+	hvr.mu.Lock()
+	defer hvr.mu.Unlock()
+
+	if authenticated && !hvr.isAuthenticated {
+		hvr.sessionNumber++
+	}
+
+	hvr.isAuthenticated = authenticated
+}
+
+// Returns a number identifying the current session, which changes on every login
+func (hvr *Client) session() int {
+	hvr.mu.RLock()
+	defer hvr.mu.RUnlock()
+
+	return hvr.sessionNumber
+}
+
+// Authenticates using the configured AuthInterface, keeping the caller's context when possible
+func (hvr *Client) authenticate(ctx context.Context) error {
+	if auth, ok := hvr.Auth.(*Auth); ok {
+		return auth.AuthenticateContext(ctx)
+	}
+
+	return hvr.Auth.Authenticate()
+}
+
+// Authenticates, unless already authenticated
+func (hvr *Client) ensureAuthenticated(ctx context.Context) error {
+	if hvr.authenticated() {
+		return nil
+	}
+
+	hvr.loginMu.Lock()
+	defer hvr.loginMu.Unlock()
+
+	// Someone else might have logged in while we were waiting
+	if hvr.authenticated() {
+		return nil
+	}
+
+	return hvr.authenticate(ctx)
+}
+
+// Authenticates again after the given session has expired. When concurrent requests find out the
+// session has expired, only the first one will login again.
+func (hvr *Client) reauthenticate(ctx context.Context, expiredSession int) error {
+	hvr.loginMu.Lock()
+	defer hvr.loginMu.Unlock()
+
+	if hvr.session() != expiredSession && hvr.authenticated() {
+		return nil
+	}
+
+	hvr.setAuthenticated(false)
+	hvr.telemetry.reauthentications.Add(ctx, 1)
+
+	return hvr.authenticate(ctx)
+}
+
 func (hvr *Client) newRequest() *resty.Request {
 	return hvr.r.NewRequest()
 }
 
+// Returns the cards available for the client's flavor
+func (hvr *Client) Cards() []CardInterface {
+	hvr.cardsMu.RLock()
+	defer hvr.cardsMu.RUnlock()
+
+	return append([]CardInterface(nil), hvr.cards...)
+}
+
+// Returns the card of the given type, or ErrCardNotSupported when the flavor has no such card
+func (hvr *Client) Card(cardType CardType) (CardInterface, error) {
+	hvr.cardsMu.RLock()
+	defer hvr.cardsMu.RUnlock()
+
+	for _, card := range hvr.cards {
+		if card.Type() == cardType {
+			return card, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrCardNotSupported, cardType)
+}
+
+// Creates a card of a product which is not one of the flavor's cards, e.g. a partner voucher served
+// from its own orders page. The card is not added to the client, see RegisterCard.
+func (hvr *Client) ProductCard(product CardProduct) *Card {
+	return newProductCard(hvr, product)
+}
+
+// Adds a card to the client, replacing the card of the same type if there's one. Useful for
+// decorating the client's cards, e.g. with a CachedCard.
+func (hvr *Client) RegisterCard(card CardInterface) {
+	hvr.cardsMu.Lock()
+	defer hvr.cardsMu.Unlock()
+
+	for i, c := range hvr.cards {
+		if c.Type() == card.Type() {
+			hvr.cards[i] = card
+			return
+		}
+	}
+
+	hvr.cards = append(hvr.cards, card)
+}
+
+// Finds out which of the flavor's cards the logged-in account actually has, by checking whether
+// the site serves a config for each card. Cards the account doesn't have are either redirected
+// away from or served without a config.
+func (hvr *Client) DiscoverCards() ([]CardInterface, error) {
+	return hvr.DiscoverCardsContext(context.Background())
+}
+
+func (hvr *Client) DiscoverCardsContext(ctx context.Context) ([]CardInterface, error) {
+	ctx, span := hvr.telemetry.startSpan(ctx, "Client.DiscoverCards")
+	defer span.End()
+
+	var discovered []CardInterface
+
+	for _, card := range hvr.Cards() {
+		c, ok := card.(*Card)
+
+		// There's no way to check cards not created by this package, so assume they're available
+		if !ok {
+			discovered = append(discovered, card)
+			continue
+		}
+
+		_, err := c.fetchConfig(ctx)
+
+		switch {
+		case err == nil:
+			discovered = append(discovered, card)
+		case errors.Is(err, ErrUnableToParseCardConfig), errors.Is(err, ErrRedirectIsNotAllowed):
+			hvr.logger.DebugContext(ctx, "card is not available", slog.String("card_type", card.Type().String()))
+		default:
+			return nil, err
+		}
+	}
+
+	return discovered, nil
+}
+
+// Gets the status of all of the cards in parallel, sharing a single session. The login happens once,
+// before fetching any of the statuses.
+func (hvr *Client) GetAllStatuses(ctx context.Context) map[CardType]CardStatusResult {
+	ctx, span := hvr.telemetry.startSpan(ctx, "Client.GetAllStatuses")
+	defer span.End()
+
+	cards := hvr.Cards()
+	results := make(map[CardType]CardStatusResult, len(cards))
+
+	if err := hvr.ensureAuthenticated(ctx); err != nil {
+		for _, card := range cards {
+			results[card.Type()] = CardStatusResult{Err: err}
+		}
+
+		return results
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for _, card := range cards {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var result CardStatusResult
+			result.Status, result.Err = card.GetStatusContext(ctx)
+
+			mu.Lock()
+			results[card.Type()] = result
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// Executes a request against one of the site's endpoints, reporting the call to the logger and the
+// telemetry
+func (hvr *Client) execute(ctx context.Context, endpoint string, req *resty.Request, method, path string) (*resty.Response, error) {
+	ctx, span := hvr.telemetry.tracer.Start(ctx, method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gohever.endpoint", endpoint),
+			attribute.String("http.request.method", method),
+		),
+	)
+
+	url, err := hvr.resolve(path)
+	if err != nil {
+		hvr.logger.LogAttrs(ctx, slog.LevelWarn, "endpoint call failed",
+			slog.String("endpoint", endpoint), errorAttr(err))
+
+		endSpan(span, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := req.SetContext(ctx).Execute(method, url.String())
+	duration := time.Since(start)
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", endpoint),
+		slog.String("method", method),
+		slog.Duration("duration", duration),
+	}
+
+	statusCode := 0
+	if resp != nil && resp.RawResponse != nil {
+		statusCode = resp.StatusCode()
+
+		attrs = append(attrs, slog.Int("status", statusCode))
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+
+	hvr.telemetry.recordEndpointCall(ctx, endpoint, method, statusCode, duration)
+
+	if err != nil {
+		hvr.logger.LogAttrs(ctx, slog.LevelWarn, "endpoint call failed", append(attrs, errorAttr(err))...)
+	} else {
+		hvr.logger.LogAttrs(ctx, slog.LevelDebug, "endpoint call", attrs...)
+	}
+
+	endSpan(span, err)
+	return resp, err
+}
+
 func (hvr *Client) redirectPolicy(req *http.Request, via []*http.Request) error {
 	// We'll be abusing redirects to check whether the user is authenticated after a request.
 	// However, redirecting is needed when authenticating because of a shitty chain they got in the
 	// flow, so we're going to allow redirecting only when the user is *not* authenticated.
 
 	// Do not catch authentication requests
-	requester := via[0].URL.Path[1:]
-	if requester == urlAuthenticate || requester == urlDeauthenticate {
+	loginPath, logoutPath := hvr.flavor.loginPath(), hvr.flavor.logoutPath()
+
+	requester := via[0].URL
+	if hvr.isPath(requester, loginPath) || hvr.isPath(requester, logoutPath) {
 		return nil
 	}
 
 	// should be the same as ErrNotAuthenticated
-	if req.URL.Path == "/logout.aspx" || (hvr.isAuthenticated && req.URL.Path[1:] == urlDeauthenticate) {
-		hvr.isAuthenticated = false
+	isAuthenticated := hvr.authenticated()
+
+	if hvr.isPath(req.URL, hvr.flavor.sessionExpiredPath()) || (isAuthenticated && hvr.isPath(req.URL, logoutPath)) {
+		hvr.logger.Info("session expired", slog.String("requester", requester.Path))
+
+		hvr.setAuthenticated(false)
 		return ErrNotAuthenticated
 	}
 
-	if hvr.isAuthenticated {
+	if isAuthenticated {
 		return ErrRedirectIsNotAllowed
 	}
 
@@ -1,7 +1,10 @@
 package gohever
 
 import (
+	"context"
 	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/go-resty/resty/v2"
@@ -12,25 +15,36 @@ import (
 type TestClientConfig struct {
 	Authenticated bool
 	Mocks         []*testutils.MockedRequest
-	Flavor        siteFlavor
+	Flavor        *Flavor
+
+	EnableManagement bool
+
+	// A path under the mock server to use as the base URL
+	BasePath string
 }
 
 func SetupTestClient(t *testing.T, config TestClientConfig) *Client {
+	if config.Flavor == nil {
+		config.Flavor = FlavorHvr()
+	}
+
+	server := testutils.NewMockServer().
+		SetupTest(t)
+
 	client := NewClient(config.Flavor, Config{
 		Credentials: BasicCredentials("TestUsername", "TestPassword"),
 		CreditCard:  BasicCreditCard("45801234567899012", "04", "2023"),
 
+		BaseURL: server.URL() + config.BasePath,
+
+		EnableManagement: config.EnableManagement,
+		// Proxy: "http://127.0.0.1:8080",
+
 		InitResty: func(r *resty.Client) {
-			// r.SetProxy("http://127.0.0.1:8080")
 			r.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
 		},
 	})
 
-	server := testutils.NewMockServer().
-		SetupTest(t)
-
-	client.r.SetBaseURL(server.URL())
-
 	for _, m := range config.Mocks {
 		server.Mock(m)
 	}
@@ -45,13 +59,109 @@ func SetupTestClient(t *testing.T, config TestClientConfig) *Client {
 func TestFlavorInitialization(t *testing.T) {
 	t.Run("should have keva and teamim when flavor if hvr", func(t *testing.T) {
 		client := SetupTestClient(t, TestClientConfig{
-			Flavor: FlavorHvr,
+			Flavor: FlavorHvr(),
+		})
+
+		keva, err := client.Card(TypeKeva)
+		assert.NoError(t, err)
+		assert.IsType(t, &Card{}, keva)
+
+		teamim, err := client.Card(TypeTeamim)
+		assert.NoError(t, err)
+		assert.IsType(t, &Card{}, teamim)
+
+		_, err = client.Card(TypeSheli)
+		assert.ErrorIs(t, err, ErrCardNotSupported)
+
+		assert.Equal(t, []CardInterface{keva, teamim}, client.Cards())
+	})
+
+	t.Run("should have sheli when flavor is mcc", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Flavor: FlavorMcc(),
+		})
+
+		sheli, err := client.Card(TypeSheli)
+		assert.NoError(t, err)
+		assert.IsType(t, &Card{}, sheli)
+
+		_, err = client.Card(TypeKeva)
+		assert.ErrorIs(t, err, ErrCardNotSupported)
+	})
+}
+
+func TestRegisterCard(t *testing.T) {
+	client := SetupTestClient(t, TestClientConfig{
+		Flavor: FlavorHvr(),
+	})
+
+	keva, _ := client.Card(TypeKeva)
+	cached := NewCachedCard(keva, CacheOptions{})
+
+	client.RegisterCard(cached)
+
+	card, err := client.Card(TypeKeva)
+	assert.NoError(t, err)
+	assert.Same(t, cached, card)
+	assert.Len(t, client.Cards(), 2)
+
+	client.RegisterCard(client.ProductCard(testProductVoucher))
+
+	voucher, err := client.Card(TypeSheli)
+	assert.NoError(t, err)
+	assert.Equal(t, testProductVoucher, voucher.(*Card).Product())
+	assert.Len(t, client.Cards(), 3)
+}
+
+func TestDiscoverCards(t *testing.T) {
+	t.Run("should only return the cards the account has", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx?food=1").Once().Status(302).Header("Location", "/default.aspx"),
+			},
+		})
+
+		cards, err := client.DiscoverCards()
+
+		assert.NoError(t, err)
+		assert.Len(t, cards, 1)
+		assert.Equal(t, TypeKeva, cards[0].Type())
+	})
+
+	t.Run("should skip cards served without a serial number", func(t *testing.T) {
+		withoutSerialNumber := regexSerialNumber.ReplaceAllString(fixtureCardConfig, "")
+
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx?food=1").Once().Status(200).Body(withoutSerialNumber),
+			},
+		})
+
+		cards, err := client.DiscoverCards()
+
+		assert.NoError(t, err)
+		assert.Len(t, cards, 1)
+		assert.Equal(t, TypeKeva, cards[0].Type())
+	})
+
+	t.Run("should fail on unexpected errors", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(302).Header("Location", "/logout.aspx"),
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Once().Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthUnsuccessful),
+			},
 		})
 
-		assert.IsType(t, &Card{}, client.Cards.Keva)
-		assert.IsType(t, &Card{}, client.Cards.Teamim)
+		_, err := client.DiscoverCards()
 
-		assert.Nil(t, client.Cards.Sheli)
+		assert.ErrorIs(t, err, ErrAuthenticatedFailed)
 	})
 }
 
@@ -119,3 +229,129 @@ func TestClientRedirectPolicy(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestClientBaseURL(t *testing.T) {
+	t.Run("should resolve all paths against the base URL", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			BasePath:      "/hever",
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/hever/orders/gift_2000.aspx").Once().Status(302).Header("Location", "/hever/logout.aspx"),
+				testutils.NewMockedRequest("GET", "/hever/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/hever/acmplt.asmx/logo?t=1234123412341").Once().Status(200),
+				testutils.NewMockedRequest("POST", "/hever/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthSuccessful),
+				testutils.NewMockedRequest("GET", "/hever/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/hever/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		status, err := newCard(client, TypeKeva).GetStatus()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 512.0, status.CurrentBalance)
+	})
+
+	t.Run("should fail requests when the base URL is invalid", func(t *testing.T) {
+		client := NewClient(FlavorHvr(), Config{
+			BaseURL: "not/absolute",
+		})
+
+		_, err := client.execute(context.Background(), endpointCardConfig, client.newRequest(), "GET", urlGiftCard)
+
+		assert.ErrorIs(t, err, ErrInvalidBaseURL)
+	})
+
+	t.Run("should fail requests when there's no flavor", func(t *testing.T) {
+		client := NewClient(nil, Config{
+			BaseURL: "http://hever.invalid/",
+		})
+
+		assert.Empty(t, client.Cards())
+
+		err := client.Auth.Authenticate()
+
+		assert.ErrorIs(t, err, ErrInvalidFlavor)
+	})
+
+	t.Run("should send requests through the proxy", func(t *testing.T) {
+		var requested []string
+
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = append(requested, r.URL.String())
+			w.Write([]byte(fixtureCardBalance))
+		}))
+		defer proxy.Close()
+
+		client := NewClient(FlavorHvr(), Config{
+			BaseURL: "http://hever.invalid/",
+			Proxy:   proxy.URL,
+		})
+		client.isAuthenticated = true
+
+		_, err := newCard(client, TypeKeva).GetBalance(WithKnownLimits(CardStatus{}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://hever.invalid/orders/gift_2000.aspx"}, requested)
+	})
+}
+
+func TestGetAllStatuses(t *testing.T) {
+	t.Run("should get the status of all cards after a single login", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Flavor: FlavorHvr(),
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Once().Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthSuccessful),
+
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardBalance),
+
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx?food=1").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx?food=1").Once().Status(200).Body("100 | 200 | 900"),
+			},
+		})
+
+		results := client.GetAllStatuses(context.Background())
+
+		assert.Len(t, results, 2)
+
+		assert.NoError(t, results[TypeKeva].Err)
+		assert.Equal(t, 512.0, results[TypeKeva].Status.CurrentBalance)
+
+		assert.NoError(t, results[TypeTeamim].Err)
+		assert.Equal(t, 100.0, results[TypeTeamim].Status.CurrentBalance)
+	})
+
+	t.Run("should report errors per card", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardBalance),
+
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx?food=1").Status(200).Body("<html></html>"),
+			},
+		})
+
+		results := client.GetAllStatuses(context.Background())
+
+		assert.NoError(t, results[TypeKeva].Err)
+		assert.ErrorIs(t, results[TypeTeamim].Err, ErrUnableToParseCardConfig)
+	})
+
+	t.Run("should report a failed login for all cards", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Once().Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Once().Status(200).Body(fixtureAuthUnsuccessful),
+			},
+		})
+
+		results := client.GetAllStatuses(context.Background())
+
+		assert.ErrorIs(t, results[TypeKeva].Err, ErrAuthenticatedFailed)
+		assert.ErrorIs(t, results[TypeTeamim].Err, ErrAuthenticatedFailed)
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+
+	"github.com/yardnsm/gohever"
+)
+
+// The server config file. Secrets can be kept out of the file by setting them to an environment
+// variable, e.g. "password": "${HEVER_PASSWORD}". Only the token, the credentials and the credit
+// cards are read this way, and only when the whole value is a variable, so other values are
+// taken as is.
+type Config struct {
+	Accounts []AccountConfig `json:"accounts"`
+}
+
+type AccountConfig struct {
+	// A name for the account, used in the logs
+	Name string `json:"name"`
+
+	// The bearer token granting access to the account
+	Token string `json:"token"`
+
+	// The flavor name, e.g. "hvr" or "mcc". Defaults to "hvr".
+	Flavor  string `json:"flavor"`
+	BaseURL string `json:"base_url,omitempty"`
+
+	Username    string             `json:"username"`
+	Password    string             `json:"password"`
+	CreditCards []CreditCardConfig `json:"credit_cards"`
+}
+
+type CreditCardConfig struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+	Month  string `json:"month"`
+	Year   string `json:"year"`
+}
+
+var regexEnvVariable = regexp.MustCompile(`^\$\{(\w+)\}$`)
+
+// An account served by the server. Every account has its own client, and so its own session.
+type account struct {
+	name   string
+	token  string
+	client *gohever.Client
+}
+
+func loadConfig(fileName string) (*Config, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse config: %w", err)
+	}
+
+	tokens := map[string]bool{}
+
+	for i := range config.Accounts {
+		acc := &config.Accounts[i]
+
+		secrets := []*string{&acc.Token, &acc.Username, &acc.Password}
+		for j := range acc.CreditCards {
+			creditCard := &acc.CreditCards[j]
+			secrets = append(secrets, &creditCard.Number, &creditCard.Month, &creditCard.Year)
+		}
+
+		for _, secret := range secrets {
+			if *secret, err = expandSecret(*secret); err != nil {
+				return nil, fmt.Errorf("account %q: %w", acc.Name, err)
+			}
+		}
+
+		if acc.Token == "" {
+			return nil, fmt.Errorf("account %q: %w", acc.Name, errNoToken)
+		}
+
+		if tokens[acc.Token] {
+			return nil, fmt.Errorf("account %q: %w", acc.Name, errDuplicateToken)
+		}
+
+		tokens[acc.Token] = true
+	}
+
+	return &config, nil
+}
+
+// Reads a value set to an environment variable, e.g. "${HEVER_PASSWORD}"
+func expandSecret(value string) (string, error) {
+	matches := regexEnvVariable.FindStringSubmatch(value)
+	if matches == nil {
+		return value, nil
+	}
+
+	secret, ok := os.LookupEnv(matches[1])
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errUnsetVariable, matches[1])
+	}
+
+	return secret, nil
+}
+
+func newAccount(config AccountConfig, logger *slog.Logger) (*account, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("account %q: %w", config.Name, errNoToken)
+	}
+
+	flavorName := config.Flavor
+	if flavorName == "" {
+		flavorName = gohever.FlavorHvr().Name
+	}
+
+	flavor, ok := gohever.LookupFlavor(flavorName)
+	if !ok {
+		return nil, fmt.Errorf("account %q: unknown flavor %q", config.Name, flavorName)
+	}
+
+	creditCards := make([]gohever.CreditCard, 0, len(config.CreditCards))
+	for _, creditCard := range config.CreditCards {
+		creditCards = append(creditCards, gohever.CreditCard(creditCard))
+	}
+
+	client := gohever.NewClient(flavor, gohever.Config{
+		Credentials: gohever.BasicCredentials(config.Username, config.Password),
+		CreditCards: gohever.BasicCreditCards(creditCards...),
+		BaseURL:     config.BaseURL,
+		Logger:      logger.With(slog.String("account", config.Name)),
+	})
+
+	return &account{
+		name:   config.Name,
+		token:  config.Token,
+		client: client,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_HEVER_PASSWORD", "TestPassword")
+
+	fileName := filepath.Join(t.TempDir(), "accounts.json")
+	os.WriteFile(fileName, []byte(`{
+  "accounts": [
+    {
+      "name": "me",
+      "token": "secret-token",
+      "flavor": "mcc",
+      "username": "TestUsername",
+      "password": "${TEST_HEVER_PASSWORD}",
+      "credit_cards": [{"name": "personal", "number": "45801234567899012", "month": "04", "year": "2030"}]
+    }
+  ]
+}`), 0o600)
+
+	config, err := loadConfig(fileName)
+	assert.NoError(t, err)
+	assert.Len(t, config.Accounts, 1)
+	assert.Equal(t, "TestPassword", config.Accounts[0].Password)
+
+	acc, err := newAccount(config.Accounts[0], discardLogger)
+	assert.NoError(t, err)
+	assert.Equal(t, gohever.FlavorMcc(), acc.client.Flavor())
+}
+
+func TestLoadConfigSecrets(t *testing.T) {
+	t.Setenv("TEST_HEVER_TOKEN", `to"ken\`)
+
+	writeConfig := func(accounts string) string {
+		fileName := filepath.Join(t.TempDir(), "accounts.json")
+		os.WriteFile(fileName, []byte(`{"accounts": [`+accounts+`]}`), 0o600)
+
+		return fileName
+	}
+
+	// Values are taken as is, unless they're a variable as a whole
+	config, err := loadConfig(writeConfig(`
+		{"name": "me", "token": "${TEST_HEVER_TOKEN}", "password": "pa$word${HOME}", "username": "$USER"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `to"ken\`, config.Accounts[0].Token)
+	assert.Equal(t, "pa$word${HOME}", config.Accounts[0].Password)
+	assert.Equal(t, "$USER", config.Accounts[0].Username)
+
+	_, err = loadConfig(writeConfig(`{"name": "me", "token": "${TEST_HEVER_UNSET}"}`))
+	assert.ErrorIs(t, err, errUnsetVariable)
+
+	_, err = loadConfig(writeConfig(`{"name": "me", "token": ""}`))
+	assert.ErrorIs(t, err, errNoToken)
+
+	_, err = loadConfig(writeConfig(`{"name": "me", "token": "token"}, {"name": "you", "token": "token"}`))
+	assert.ErrorIs(t, err, errDuplicateToken)
+	assert.ErrorContains(t, err, `account "you"`)
+}
+
+func TestNewAccount(t *testing.T) {
+	_, err := newAccount(AccountConfig{Name: "me"}, discardLogger)
+	assert.ErrorIs(t, err, errNoToken)
+
+	_, err = newAccount(AccountConfig{Name: "me", Token: "token", Flavor: "nope"}, discardLogger)
+	assert.ErrorContains(t, err, "unknown flavor")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Keeps the responses of requests made with an idempotency key, so retrying a request won't
+// repeat it. Concurrent requests with the same key wait for the first one to finish.
+type idempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotentResponse
+}
+
+type idempotentResponse struct {
+	done    chan struct{}
+	created time.Time
+
+	// Identifies the request the key was used with, so the key can't be reused for another one
+	fingerprint string
+
+	status int
+	body   []byte
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotentResponse),
+	}
+}
+
+// Returns the response for the key, or nil with ok when the caller should make the request and
+// then call either finish or abort. When the key was used with another request, ok is false.
+func (store *idempotencyStore) begin(key, fingerprint string) (response *idempotentResponse, ok bool) {
+	store.mu.Lock()
+
+	now := store.now()
+	for k, entry := range store.entries {
+		if now.Sub(entry.created) > store.ttl && entry.finished() {
+			delete(store.entries, k)
+		}
+	}
+
+	entry, exists := store.entries[key]
+	if !exists {
+		store.entries[key] = &idempotentResponse{
+			done:        make(chan struct{}),
+			created:     now,
+			fingerprint: fingerprint,
+		}
+
+		store.mu.Unlock()
+		return nil, true
+	}
+
+	store.mu.Unlock()
+
+	if entry.fingerprint != fingerprint {
+		return nil, false
+	}
+
+	<-entry.done
+
+	// The first request was aborted, so try again
+	if entry.body == nil {
+		return store.begin(key, fingerprint)
+	}
+
+	return entry, true
+}
+
+// Stores the response of a request started with begin
+func (store *idempotencyStore) finish(key string, status int, body []byte) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry := store.entries[key]
+	entry.status, entry.body = status, body
+
+	close(entry.done)
+}
+
+// Forgets a request started with begin, so it could be retried
+func (store *idempotencyStore) abort(key string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry := store.entries[key]
+	delete(store.entries, key)
+
+	close(entry.done)
+}
+
+func (response *idempotentResponse) finished() bool {
+	select {
+	case <-response.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+// Command gohever-server exposes the cards of HEVER accounts over a JSON REST API, for tools not
+// written in Go. See openapi.yaml for the API.
+//
+// Usage:
+//
+//	gohever-server -config accounts.json -addr :8080
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	configFile := flag.String("config", "accounts.json", "the accounts config file")
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(*configFile, *addr, logger); err != nil {
+		logger.Error("server failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func run(configFile, addr string, logger *slog.Logger) error {
+	config, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	var accounts []*account
+	for _, accountConfig := range config.Accounts {
+		acc, err := newAccount(accountConfig, logger)
+		if err != nil {
+			return err
+		}
+
+		accounts = append(accounts, acc)
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           NewServer(accounts, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("listening", slog.String("addr", addr), slog.Int("accounts", len(accounts)))
+
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
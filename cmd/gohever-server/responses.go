@@ -0,0 +1,131 @@
+package main
+
+import "github.com/yardnsm/gohever"
+
+// The request and response bodies of the API, see openapi.yaml
+
+type estimateRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+type loadRequest struct {
+	Amount int32 `json:"amount"`
+
+	// The name of the credit card to load with, the default one is used when empty
+	CreditCard string `json:"credit_card,omitempty"`
+
+	// Retry declined loads with the next credit cards
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type cardResponse struct {
+	Type string `json:"type"`
+}
+
+type factorResponse struct {
+	Factor float64 `json:"factor"`
+	Amount float64 `json:"amount"`
+}
+
+type statusResponse struct {
+	Type    string           `json:"type"`
+	Factors []factorResponse `json:"factors"`
+
+	MaxMonthlyAmount int `json:"max_monthly_amount"`
+	MaxOnCardAmount  int `json:"max_on_card_amount"`
+
+	CurrentBalance         float64 `json:"current_balance"`
+	RemainingMonthlyAmount float64 `json:"remaining_monthly_amount"`
+	RemainingOnCardAmount  float64 `json:"remaining_on_card_amount"`
+
+	MonthlyUsage float64 `json:"monthly_usage"`
+	Leftovers    float64 `json:"leftovers"`
+}
+
+type historyItemResponse struct {
+	Id           string  `json:"id"`
+	Date         string  `json:"date"`
+	Action       string  `json:"action"`
+	BusinessName string  `json:"business_name"`
+	Amount       float64 `json:"amount"`
+}
+
+type estimateResponse struct {
+	Total         float64 `json:"total"`
+	TotalFactored float64 `json:"total_factored"`
+
+	Required         float64 `json:"required"`
+	RequiredFactored float64 `json:"required_factored"`
+
+	Leftovers float64          `json:"leftovers"`
+	Factors   []factorResponse `json:"factors"`
+}
+
+type loadResponse struct {
+	Status     string `json:"status"`
+	LoadNumber string `json:"load_number"`
+	Message    string `json:"message"`
+	CreditCard string `json:"credit_card"`
+}
+
+func newFactorsResponse(factors []gohever.CardFactor) []factorResponse {
+	response := []factorResponse{}
+	for _, factor := range factors {
+		response = append(response, factorResponse{Factor: factor.Factor, Amount: factor.Amount})
+	}
+
+	return response
+}
+
+func newStatusResponse(cardType gohever.CardType, status *gohever.CardStatus) statusResponse {
+	return statusResponse{
+		Type:    cardType.String(),
+		Factors: newFactorsResponse(status.Factors),
+
+		MaxMonthlyAmount: status.MaxMonthlyAmount,
+		MaxOnCardAmount:  status.MaxOnCardAmount,
+
+		CurrentBalance:         status.CurrentBalance,
+		RemainingMonthlyAmount: status.RemainingMonthlyAmount,
+		RemainingOnCardAmount:  status.RemainingOnCardAmount,
+
+		MonthlyUsage: status.MonthlyUsage,
+		Leftovers:    status.Leftovers,
+	}
+}
+
+func newHistoryItemResponse(item gohever.CardHistoryItem) historyItemResponse {
+	return historyItemResponse{
+		Id:           item.Id,
+		Date:         item.Date,
+		Action:       item.ActionType.String(),
+		BusinessName: item.BusinessName,
+		Amount:       item.Amount,
+	}
+}
+
+func newEstimateResponse(estimate *gohever.CardEstimate) estimateResponse {
+	return estimateResponse{
+		Total:         estimate.Total,
+		TotalFactored: estimate.TotalFactored,
+
+		Required:         estimate.Required,
+		RequiredFactored: estimate.RequiredFactored,
+
+		Leftovers: estimate.Leftovers,
+		Factors:   newFactorsResponse(estimate.Factors),
+	}
+}
+
+func newLoadResponse(result *gohever.LoadResult) loadResponse {
+	return loadResponse{
+		Status:     result.Status.String(),
+		LoadNumber: result.LoadNumber,
+		Message:    result.RawMessage,
+		CreditCard: result.CreditCard,
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	headerReplayed       = "Idempotent-Replayed"
+
+	idempotencyTTL = 24 * time.Hour
+)
+
+var (
+	errNoToken               = errors.New("no token was configured")
+	errDuplicateToken        = errors.New("the token is already used by another account")
+	errUnsetVariable         = errors.New("environment variable is not set")
+	errUnauthorized          = errors.New("missing or invalid bearer token")
+	errNoIdempotencyKey      = errors.New("the Idempotency-Key header is required")
+	errIdempotencyKeyReused  = errors.New("the idempotency key was already used with another request")
+	errInvalidAmount         = errors.New("amount should be a positive number")
+	errUnknownCardType       = errors.New("unknown card type")
+	errInvalidRequestPayload = errors.New("invalid request payload")
+
+	errLoadOutcomeUnknown = errors.New("the outcome of the load is unknown, check the card before loading it again")
+)
+
+// Serves the cards of the configured accounts over a JSON REST API
+type Server struct {
+	accounts    []*account
+	idempotency *idempotencyStore
+	logger      *slog.Logger
+	mux         *http.ServeMux
+}
+
+func NewServer(accounts []*account, logger *slog.Logger) *Server {
+	server := &Server{
+		accounts:    accounts,
+		idempotency: newIdempotencyStore(idempotencyTTL),
+		logger:      logger,
+		mux:         http.NewServeMux(),
+	}
+
+	server.mux.HandleFunc("GET /openapi.yaml", server.handleOpenAPI)
+
+	server.mux.HandleFunc("GET /cards", server.authorized(server.handleListCards))
+	server.mux.HandleFunc("GET /cards/{type}/status", server.authorized(server.handleStatus))
+	server.mux.HandleFunc("GET /cards/{type}/history", server.authorized(server.handleHistory))
+	server.mux.HandleFunc("POST /cards/{type}/estimate", server.authorized(server.handleEstimate))
+	server.mux.HandleFunc("POST /cards/{type}/load", server.authorized(server.handleLoad))
+
+	return server
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+type accountHandler func(w http.ResponseWriter, r *http.Request, acc *account)
+
+// Resolves the account of the request by its bearer token
+func (server *Server) authorized(handler accountHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if ok && token != "" {
+			for _, acc := range server.accounts {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(acc.token)) == 1 {
+					handler(w, r, acc)
+					return
+				}
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		server.writeError(w, r, http.StatusUnauthorized, errUnauthorized)
+	}
+}
+
+// Returns the card of the request's path
+func (server *Server) card(r *http.Request, acc *account) (gohever.CardInterface, error) {
+	cardType := r.PathValue("type")
+
+	for _, card := range acc.client.Cards() {
+		if card.Type().String() == cardType {
+			return card, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", errUnknownCardType, cardType)
+}
+
+func (server *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+func (server *Server) handleListCards(w http.ResponseWriter, r *http.Request, acc *account) {
+	cards := []cardResponse{}
+	for _, card := range acc.client.Cards() {
+		cards = append(cards, cardResponse{Type: card.Type().String()})
+	}
+
+	server.writeJSON(w, http.StatusOK, cards)
+}
+
+func (server *Server) handleStatus(w http.ResponseWriter, r *http.Request, acc *account) {
+	card, err := server.card(r, acc)
+	if err != nil {
+		server.writeError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	status, err := card.GetStatusContext(r.Context())
+	if err != nil {
+		server.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	server.writeJSON(w, http.StatusOK, newStatusResponse(card.Type(), status))
+}
+
+func (server *Server) handleHistory(w http.ResponseWriter, r *http.Request, acc *account) {
+	card, err := server.card(r, acc)
+	if err != nil {
+		server.writeError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	history, err := card.GetHistoryContext(r.Context())
+	if err != nil {
+		server.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	items := []historyItemResponse{}
+	for _, item := range *history {
+		items = append(items, newHistoryItemResponse(item))
+	}
+
+	server.writeJSON(w, http.StatusOK, items)
+}
+
+func (server *Server) handleEstimate(w http.ResponseWriter, r *http.Request, acc *account) {
+	card, err := server.card(r, acc)
+	if err != nil {
+		server.writeError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	var req estimateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		server.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Amount <= 0 {
+		server.writeError(w, r, http.StatusBadRequest, errInvalidAmount)
+		return
+	}
+
+	status, err := card.GetStatusContext(r.Context())
+	if err != nil {
+		server.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	estimate, err := status.Estimate(req.Amount)
+	if err != nil {
+		server.writeError(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	server.writeJSON(w, http.StatusOK, newEstimateResponse(estimate))
+}
+
+func (server *Server) handleLoad(w http.ResponseWriter, r *http.Request, acc *account) {
+	key := r.Header.Get(headerIdempotencyKey)
+	if key == "" {
+		server.writeError(w, r, http.StatusBadRequest, errNoIdempotencyKey)
+		return
+	}
+
+	card, err := server.card(r, acc)
+	if err != nil {
+		server.writeError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	var req loadRequest
+	if err := decodeJSON(r, &req); err != nil {
+		server.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Amount <= 0 {
+		server.writeError(w, r, http.StatusBadRequest, errInvalidAmount)
+		return
+	}
+
+	// Keys are scoped to the account, and can't be reused for another load
+	storeKey := acc.token + "\x00" + key
+	fingerprint := fmt.Sprintf("%s:%d:%s:%t", card.Type(), req.Amount, req.CreditCard, req.Fallback)
+
+	previous, ok := server.idempotency.begin(storeKey, fingerprint)
+	if !ok {
+		server.writeError(w, r, http.StatusUnprocessableEntity, errIdempotencyKeyReused)
+		return
+	}
+
+	if previous != nil {
+		w.Header().Set(headerReplayed, "true")
+		server.writeRaw(w, previous.status, previous.body)
+		return
+	}
+
+	status, body, sent := server.load(r, card, req)
+
+	// Failures before sending the load are not kept, so the load could be retried using the same
+	// key. Once sent, the card might have been loaded even if the load failed, so retrying it could
+	// load the card twice.
+	if !sent && status >= http.StatusInternalServerError {
+		server.idempotency.abort(storeKey)
+	} else {
+		server.idempotency.finish(storeKey, status, body)
+	}
+
+	server.writeRaw(w, status, body)
+}
+
+// Loads the card, reporting whether the load was sent to the site
+func (server *Server) load(r *http.Request, card gohever.CardInterface, req loadRequest) (int, []byte, bool) {
+	status, err := card.GetStatusContext(r.Context())
+	if err != nil {
+		code, body := server.errorBody(r, http.StatusBadGateway, err)
+		return code, body, false
+	}
+
+	var opts []gohever.LoadOption
+	if req.CreditCard != "" {
+		opts = append(opts, gohever.WithCreditCard(req.CreditCard))
+	}
+
+	if req.Fallback {
+		opts = append(opts, gohever.WithCreditCardFallback())
+	}
+
+	// A load that was sent can't be called back, so it's not cancelled when the client goes away
+	result, err := card.LoadContext(context.WithoutCancel(r.Context()), *status, req.Amount, opts...)
+	if errors.Is(err, gohever.ErrCreditCardNotFound) {
+		code, body := server.errorBody(r, http.StatusBadRequest, err)
+		return code, body, false
+	}
+
+	if err != nil {
+		code, body := server.errorBody(r, http.StatusBadGateway, fmt.Errorf("%w: %w", errLoadOutcomeUnknown, err))
+		return code, body, true
+	}
+
+	code := http.StatusOK
+	if result.Status != gohever.StatusSuccess {
+		code = http.StatusUnprocessableEntity
+	}
+
+	return code, encodeJSON(newLoadResponse(result)), true
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidRequestPayload, err)
+	}
+
+	return nil
+}
+
+func encodeJSON(v any) []byte {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(v)
+
+	return buf.Bytes()
+}
+
+func (server *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	server.writeRaw(w, status, encodeJSON(v))
+}
+
+func (server *Server) writeRaw(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func (server *Server) errorBody(r *http.Request, status int, err error) (int, []byte) {
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelWarn
+	}
+
+	server.logger.Log(r.Context(), level, "request failed",
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.String("error", err.Error()))
+
+	return status, encodeJSON(errorResponse{Error: err.Error()})
+}
+
+func (server *Server) writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	status, body := server.errorBody(r, status, err)
+	server.writeRaw(w, status, body)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+const testToken = "secret-token"
+
+const (
+	fixtureCardConfig = `<html><body>
+<script>
+	var gift_card_factor1 = 0.7;
+	var gift_card_factor2 = 0.8;
+	var gift_card_factor3 = 0.9;
+	var gift_card_factor1_price = 1000;
+	var gift_card_factor2_price = 1500;
+	var gift_card_factor3_price = 2000;
+	var max_month_load = 4500;
+	var max_on_card = 1000;
+</script>
+<form method="post"><input type="hidden" name="sn" value="12345678-9abc-def1-2345-6789abcdef12"></form>
+<table>
+<tr class="historyRows" id="1"><td>14/03/2024</td><td>רכישה</td><td>סופר</td><td>150</td></tr>
+</table>
+</body></html>`
+
+	fixtureCardBalance = `512 | 3,988 | 488`
+
+	fixtureLoadSuccess = `<html><body>
+<div id="msg_ok">בקשת טעינת הכרטיס בוצעה. מספר ההזמנה: 12344321</div>
+<script>if ( 2 == 1 ) { show_msg(); }</script>
+</body></html>`
+)
+
+// The balance request of the card status, telling it apart from the load request
+var balanceForm = testutils.FormData{
+	"balance_only":           "1",
+	"current_max_month_load": "4500",
+	"current_max_load":       "1000",
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Starts the API server over a single account, which site is at the given URL
+func setupServer(t *testing.T, baseURL string) *httptest.Server {
+	acc, err := newAccount(AccountConfig{
+		Name:     "me",
+		Token:    testToken,
+		BaseURL:  baseURL,
+		Username: "TestUsername",
+		Password: "TestPassword",
+		CreditCards: []CreditCardConfig{
+			{Name: "declined", Number: "4580000000000000", Month: "04", Year: "2030"},
+			{Name: "personal", Number: "45801234567899012", Month: "04", Year: "2030"},
+		},
+	}, discardLogger)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(NewServer([]*account{acc}, discardLogger))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Starts the API server over the mock server, already logged in
+func setupMockedServer(t *testing.T, mocks ...*testutils.MockedRequest) *httptest.Server {
+	mockServer := testutils.NewMockServer().SetupTest(t)
+
+	mocks = append([]*testutils.MockedRequest{
+		testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Once().Status(200).Body(`<html><body>
+<form id="signinForm"><input type="hidden" name="bs" value="1"></form>
+<img src="acmplt.asmx/logo?t=1234">
+</body></html>`),
+		testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234").Once().Status(200),
+		testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Once().Status(200).Body(`<html></html>`),
+	}, mocks...)
+
+	for _, mock := range mocks {
+		mockServer.Mock(mock)
+	}
+
+	return setupServer(t, mockServer.URL())
+}
+
+func setupFakeServer(t *testing.T) (*testutils.FakeHever, *httptest.Server) {
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username:            "TestUsername",
+		Password:            "TestPassword",
+		DeclinedCreditCards: []string{"4580000000000000"},
+	}).SetupTest(t)
+
+	return fake, setupServer(t, fake.URL())
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   map[string]any
+	list   []map[string]any
+}
+
+func request(t *testing.T, server *httptest.Server, method, path, body string, headers ...string) response {
+	req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+
+	result := response{status: resp.StatusCode, header: resp.Header}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		assert.NoError(t, json.Unmarshal(data, &result.list))
+	} else if resp.Header.Get("Content-Type") == "application/json" {
+		assert.NoError(t, json.Unmarshal(data, &result.body))
+	}
+
+	return result
+}
+
+func TestAuthorization(t *testing.T) {
+	server := setupServer(t, "http://hever.invalid/")
+
+	for _, header := range []string{"", "Bearer", "Bearer wrong", "Basic " + testToken} {
+		req, _ := http.NewRequest("GET", server.URL+"/cards", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+		resp.Body.Close()
+	}
+
+	// The spec is public
+	resp, err := http.Get(server.URL + "/openapi.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spec, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	for _, path := range []string{"/cards:", "/cards/{type}/status:", "/cards/{type}/history:", "/cards/{type}/estimate:", "/cards/{type}/load:"} {
+		assert.Contains(t, string(spec), path)
+	}
+}
+
+func TestListCards(t *testing.T) {
+	server := setupServer(t, "http://hever.invalid/")
+
+	resp := request(t, server, "GET", "/cards", "")
+
+	assert.Equal(t, http.StatusOK, resp.status)
+	assert.Equal(t, []map[string]any{
+		{"type": "keva"},
+		{"type": "teamim"},
+	}, resp.list)
+}
+
+func TestGetStatus(t *testing.T) {
+	t.Run("should return the card status", func(t *testing.T) {
+		server := setupMockedServer(t,
+			testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardBalance),
+		)
+
+		resp := request(t, server, "GET", "/cards/keva/status", "")
+
+		assert.Equal(t, http.StatusOK, resp.status)
+		assert.Equal(t, "keva", resp.body["type"])
+		assert.Equal(t, 512.0, resp.body["current_balance"])
+		assert.Equal(t, 3988.0, resp.body["remaining_monthly_amount"])
+		assert.Equal(t, 4500.0, resp.body["max_monthly_amount"])
+		assert.Len(t, resp.body["factors"], 3)
+		assert.NotContains(t, resp.body, "serial_number")
+	})
+
+	t.Run("should fail on unknown cards", func(t *testing.T) {
+		server := setupServer(t, "http://hever.invalid/")
+
+		resp := request(t, server, "GET", "/cards/sheli/status", "")
+
+		assert.Equal(t, http.StatusNotFound, resp.status)
+		assert.Contains(t, resp.body["error"], "unknown card type")
+	})
+
+	t.Run("should fail when the site fails", func(t *testing.T) {
+		server := setupMockedServer(t,
+			testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body("<html></html>"),
+		)
+
+		resp := request(t, server, "GET", "/cards/keva/status", "")
+
+		assert.Equal(t, http.StatusBadGateway, resp.status)
+		assert.Contains(t, resp.body["error"], "card config")
+	})
+}
+
+func TestGetHistory(t *testing.T) {
+	server := setupMockedServer(t,
+		testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+	)
+
+	resp := request(t, server, "GET", "/cards/keva/history", "")
+
+	assert.Equal(t, http.StatusOK, resp.status)
+	assert.Equal(t, []map[string]any{
+		{"id": "1", "date": "14/03/2024", "action": "purchase", "business_name": "סופר", "amount": 150.0},
+	}, resp.list)
+}
+
+func TestEstimate(t *testing.T) {
+	_, server := setupFakeServer(t)
+
+	t.Run("should estimate the load", func(t *testing.T) {
+		resp := request(t, server, "POST", "/cards/keva/estimate", `{"amount": 400}`)
+
+		assert.Equal(t, http.StatusOK, resp.status)
+		assert.Equal(t, 400.0, resp.body["total"])
+		assert.Equal(t, 280.0, resp.body["total_factored"])
+	})
+
+	t.Run("should fail above the limits", func(t *testing.T) {
+		resp := request(t, server, "POST", "/cards/keva/estimate", `{"amount": 1500}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.status)
+		assert.Contains(t, resp.body["error"], "max on card")
+	})
+
+	t.Run("should fail on invalid payloads", func(t *testing.T) {
+		for _, body := range []string{``, `{"amount": "a lot"}`, `{"amount": -1}`, `{"amount": 1, "extra": 1}`} {
+			resp := request(t, server, "POST", "/cards/keva/estimate", body)
+			assert.Equal(t, http.StatusBadRequest, resp.status, body)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should load the card", func(t *testing.T) {
+		fake, server := setupFakeServer(t)
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 400, "fallback": true}`,
+			headerIdempotencyKey, "load-1")
+
+		assert.Equal(t, http.StatusOK, resp.status)
+		assert.Equal(t, "success", resp.body["status"])
+		assert.Equal(t, "personal", resp.body["credit_card"])
+		assert.NotEmpty(t, resp.body["load_number"])
+
+		card, _ := fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 400.0, card.Balance)
+	})
+
+	t.Run("should report declined loads", func(t *testing.T) {
+		_, server := setupFakeServer(t)
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 400}`,
+			headerIdempotencyKey, "load-1")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.status)
+		assert.Equal(t, "error", resp.body["status"])
+		assert.Equal(t, "declined", resp.body["credit_card"])
+	})
+
+	t.Run("should require an idempotency key", func(t *testing.T) {
+		_, server := setupFakeServer(t)
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 400}`)
+
+		assert.Equal(t, http.StatusBadRequest, resp.status)
+	})
+
+	t.Run("should fail on unknown credit cards", func(t *testing.T) {
+		_, server := setupFakeServer(t)
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 400, "credit_card": "nope"}`,
+			headerIdempotencyKey, "load-1")
+
+		assert.Equal(t, http.StatusBadRequest, resp.status)
+	})
+
+	t.Run("should load once per idempotency key", func(t *testing.T) {
+		fake, server := setupFakeServer(t)
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			responses []response
+		)
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+					headerIdempotencyKey, "load-1")
+
+				mu.Lock()
+				responses = append(responses, resp)
+				mu.Unlock()
+			}()
+		}
+
+		wg.Wait()
+
+		replayed := 0
+		for _, resp := range responses {
+			assert.Equal(t, http.StatusOK, resp.status)
+			assert.Equal(t, responses[0].body, resp.body)
+
+			if resp.header.Get(headerReplayed) == "true" {
+				replayed++
+			}
+		}
+
+		assert.Equal(t, 4, replayed)
+
+		card, _ := fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 100.0, card.Balance)
+		assert.Len(t, card.History, 1)
+
+		// Another key makes another load
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-2")
+		assert.Equal(t, http.StatusOK, resp.status)
+
+		card, _ = fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 200.0, card.Balance)
+	})
+
+	t.Run("should retry the load when the site fails before sending it", func(t *testing.T) {
+		server := setupMockedServer(t,
+			testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(`<html></html>`),
+			testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardBalance).MatchFormData(balanceForm),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureLoadSuccess),
+		)
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-1")
+		assert.Equal(t, http.StatusBadGateway, resp.status)
+		assert.NotContains(t, resp.body["error"], errLoadOutcomeUnknown.Error())
+
+		resp = request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-1")
+		assert.Equal(t, http.StatusOK, resp.status)
+		assert.Equal(t, "12344321", resp.body["load_number"])
+	})
+
+	t.Run("should not retry a sent load which failed", func(t *testing.T) {
+		server := setupMockedServer(t,
+			testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardBalance).MatchFormData(balanceForm),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Once().Status(302).Header("Location", "/default.aspx"),
+		)
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-1")
+		assert.Equal(t, http.StatusBadGateway, resp.status)
+		assert.Contains(t, resp.body["error"], errLoadOutcomeUnknown.Error())
+
+		// The load is not sent again, which the mocks would have failed on
+		retry := request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-1")
+		assert.Equal(t, http.StatusBadGateway, retry.status)
+		assert.Equal(t, "true", retry.header.Get(headerReplayed))
+		assert.Equal(t, resp.body, retry.body)
+	})
+
+	t.Run("should not reuse an idempotency key for another load", func(t *testing.T) {
+		_, server := setupFakeServer(t)
+
+		request(t, server, "POST", "/cards/keva/load", `{"amount": 100, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-1")
+
+		resp := request(t, server, "POST", "/cards/keva/load", `{"amount": 200, "credit_card": "personal"}`,
+			headerIdempotencyKey, "load-1")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.status)
+		assert.Contains(t, resp.body["error"], "idempotency key")
+	})
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+
+	now := time.Date(2024, time.March, 14, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	previous, ok := store.begin("key", "a")
+	assert.True(t, ok)
+	assert.Nil(t, previous)
+
+	store.abort("key")
+
+	// Aborted requests can be retried
+	previous, ok = store.begin("key", "a")
+	assert.True(t, ok)
+	assert.Nil(t, previous)
+
+	store.finish("key", http.StatusOK, []byte(`{}`))
+
+	previous, ok = store.begin("key", "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte(`{}`), previous.body)
+
+	// Keys expire
+	now = now.Add(2 * time.Hour)
+
+	previous, ok = store.begin("key", "b")
+	assert.True(t, ok)
+	assert.Nil(t, previous)
+}
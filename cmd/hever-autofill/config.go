@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+const (
+	defaultHistory  = "autofill-history.jsonl"
+	defaultLocation = "Asia/Jerusalem"
+)
+
+var (
+	errNoRules     = errors.New("no rules were configured")
+	errInvalidRule = errors.New("invalid rule")
+)
+
+// The daemon config file. Environment variables within it are expanded, so secrets can be kept
+// out of the file, e.g. "password": "${HEVER_PASSWORD}".
+type Config struct {
+	// The flavor name, e.g. "hvr" or "mcc". Defaults to "hvr".
+	Flavor  string `json:"flavor"`
+	BaseURL string `json:"base_url,omitempty"`
+
+	Username    string             `json:"username"`
+	Password    string             `json:"password"`
+	CreditCards []CreditCardConfig `json:"credit_cards"`
+
+	// Evaluate the rules without loading anything
+	DryRun bool `json:"dry_run"`
+
+	// The file to append the runs to. Defaults to "autofill-history.jsonl".
+	HistoryFile string `json:"history_file"`
+
+	// The time zone the schedules are in. Defaults to "Asia/Jerusalem".
+	TimeZone string `json:"time_zone"`
+
+	Notify NotifyConfig `json:"notify"`
+	Rules  []RuleConfig `json:"rules"`
+}
+
+type CreditCardConfig struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+	Month  string `json:"month"`
+	Year   string `json:"year"`
+}
+
+type NotifyConfig struct {
+	// A URL to POST every load, failure and dry run to, as JSON
+	WebhookURL string `json:"webhook_url"`
+}
+
+// A rule for filling a card, for example:
+//
+//	{"name": "monthly", "card": "keva", "schedule": "0 9 1 * *"}
+//	{"name": "top up", "card": "teamim", "schedule": "*/30 * * * *", "below": 200, "fill_to": 800}
+type RuleConfig struct {
+	Name string `json:"name"`
+	Card string `json:"card"`
+
+	// When to evaluate the rule, see Schedule
+	Schedule string `json:"schedule"`
+
+	// Only fill the card when its balance is below this amount
+	Below float64 `json:"below,omitempty"`
+
+	// The balance to fill the card up to, within the monthly limit. The card is filled to its max
+	// when omitted.
+	FillTo float64 `json:"fill_to,omitempty"`
+
+	// The credit card to load with, and whether to fall back to the next ones when declined
+	CreditCard string `json:"credit_card,omitempty"`
+	Fallback   bool   `json:"fallback,omitempty"`
+}
+
+func loadConfig(fileName string) (*Config, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &config); err != nil {
+		return nil, fmt.Errorf("unable to parse config: %w", err)
+	}
+
+	if config.HistoryFile == "" {
+		config.HistoryFile = defaultHistory
+	}
+
+	if config.TimeZone == "" {
+		config.TimeZone = defaultLocation
+	}
+
+	return &config, nil
+}
+
+func (config *Config) newClient(logger *slog.Logger) (*gohever.Client, error) {
+	flavorName := config.Flavor
+	if flavorName == "" {
+		flavorName = gohever.FlavorHvr().Name
+	}
+
+	flavor, ok := gohever.LookupFlavor(flavorName)
+	if !ok {
+		return nil, fmt.Errorf("unknown flavor %q", flavorName)
+	}
+
+	creditCards := make([]gohever.CreditCard, 0, len(config.CreditCards))
+	for _, creditCard := range config.CreditCards {
+		creditCards = append(creditCards, gohever.CreditCard(creditCard))
+	}
+
+	return gohever.NewClient(flavor, gohever.Config{
+		Credentials: gohever.BasicCredentials(config.Username, config.Password),
+		CreditCards: gohever.BasicCreditCards(creditCards...),
+		BaseURL:     config.BaseURL,
+		Logger:      logger,
+	}), nil
+}
+
+func (config *Config) rules() ([]*Rule, error) {
+	if len(config.Rules) == 0 {
+		return nil, errNoRules
+	}
+
+	location, err := time.LoadLocation(config.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*Rule
+	for _, ruleConfig := range config.Rules {
+		rule, err := newRule(ruleConfig, location)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
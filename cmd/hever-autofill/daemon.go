@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+type DaemonOptions struct {
+	// Evaluate the rules without loading anything
+	DryRun bool
+
+	// Notified on loads, failures and dry runs. Optional.
+	Notifier Notifier
+
+	Logger *slog.Logger
+
+	// Used for timing the rules, time.Now is used when nil
+	Now func() time.Time
+}
+
+// Runs the rules on their schedules
+type Daemon struct {
+	client  *gohever.Client
+	rules   []*Rule
+	history *History
+	options DaemonOptions
+	logger  *slog.Logger
+}
+
+func NewDaemon(client *gohever.Client, rules []*Rule, history *History, options DaemonOptions) *Daemon {
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(discard{}, nil))
+	}
+
+	return &Daemon{
+		client:  client,
+		rules:   rules,
+		history: history,
+		options: options,
+		logger:  logger,
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+// Runs the rules on their schedules until the context is done
+func (daemon *Daemon) Run(ctx context.Context) error {
+	for {
+		now := daemon.options.Now()
+
+		// Find the rules due next
+		var next time.Time
+		var due []*Rule
+
+		for _, rule := range daemon.rules {
+			t := rule.next(now)
+			if t.IsZero() {
+				continue
+			}
+
+			switch {
+			case next.IsZero() || t.Before(next):
+				next, due = t, []*Rule{rule}
+			case t.Equal(next):
+				due = append(due, rule)
+			}
+		}
+
+		if next.IsZero() {
+			daemon.logger.WarnContext(ctx, "no rule is scheduled to run")
+			<-ctx.Done()
+			return nil
+		}
+
+		daemon.logger.DebugContext(ctx, "waiting for the next rules", slog.Time("at", next), slog.Int("rules", len(due)))
+
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		for _, rule := range due {
+			daemon.RunRule(ctx, rule)
+		}
+	}
+}
+
+// Runs every rule once, regardless of their schedules
+func (daemon *Daemon) RunAll(ctx context.Context) []Run {
+	var runs []Run
+	for _, rule := range daemon.rules {
+		runs = append(runs, daemon.RunRule(ctx, rule))
+	}
+
+	return runs
+}
+
+// Evaluates the rule and loads the card if needed. The run is recorded in the history and
+// notified, unless it was skipped.
+func (daemon *Daemon) RunRule(ctx context.Context, rule *Rule) Run {
+	run := daemon.evaluate(ctx, rule)
+
+	logger := daemon.logger.With(
+		slog.String("rule", run.Rule),
+		slog.String("card", run.Card),
+		slog.String("status", string(run.Status)))
+
+	if run.Status == RunFailed {
+		logger.ErrorContext(ctx, "rule failed", slog.String("error", run.Message))
+	} else {
+		logger.InfoContext(ctx, run.summary())
+	}
+
+	if err := daemon.history.Append(run); err != nil {
+		logger.ErrorContext(ctx, "unable to record the run", slog.String("error", err.Error()))
+	}
+
+	if run.Status != RunSkipped && daemon.options.Notifier != nil {
+		if err := daemon.options.Notifier.Notify(ctx, run); err != nil {
+			logger.ErrorContext(ctx, "unable to notify the run", slog.String("error", err.Error()))
+		}
+	}
+
+	return run
+}
+
+func (daemon *Daemon) evaluate(ctx context.Context, rule *Rule) Run {
+	run := Run{
+		Rule: rule.Name,
+		Card: rule.Card,
+		Time: daemon.options.Now(),
+	}
+
+	fail := func(err error) Run {
+		run.Status = RunFailed
+		run.Message = err.Error()
+		return run
+	}
+
+	card, err := daemon.client.Card(rule.cardType)
+	if err != nil {
+		return fail(err)
+	}
+
+	status, err := card.GetStatusContext(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	run.Balance = status.CurrentBalance
+
+	amount, reason := rule.plan(status)
+	if amount == 0 {
+		run.Status = RunSkipped
+		run.Message = reason
+		return run
+	}
+
+	estimate, err := status.Estimate(amount)
+	if err != nil {
+		return fail(err)
+	}
+
+	run.Amount = estimate.Total
+	run.Cost = estimate.TotalFactored
+
+	if daemon.options.DryRun {
+		run.Status = RunDryRun
+		return run
+	}
+
+	result, err := card.LoadContext(ctx, *status, int32(amount), rule.loadOptions()...)
+	if err != nil {
+		return fail(err)
+	}
+
+	run.CreditCard = result.CreditCard
+	run.LoadNumber = result.LoadNumber
+
+	if result.Status != gohever.StatusSuccess {
+		run.Status = RunFailed
+		run.Message = result.RawMessage
+		return run
+	}
+
+	run.Status = RunLoaded
+	return run
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+var testNow = time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	runs []Run
+}
+
+func (notifier *recordingNotifier) Notify(ctx context.Context, run Run) error {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	notifier.runs = append(notifier.runs, run)
+	return nil
+}
+
+type testDaemon struct {
+	*Daemon
+	fake     *testutils.FakeHever
+	notifier *recordingNotifier
+}
+
+func setupTestDaemon(t *testing.T, keva *testutils.FakeCard, dryRun bool, rules ...RuleConfig) *testDaemon {
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username:            "TestUsername",
+		Password:            "TestPassword",
+		DeclinedCreditCards: []string{"4580000000000000"},
+		Cards:               map[string]*testutils.FakeCard{testutils.FakeCardKeva: keva},
+	}).SetupTest(t)
+
+	config := Config{
+		BaseURL:  fake.URL(),
+		Username: "TestUsername",
+		Password: "TestPassword",
+		CreditCards: []CreditCardConfig{
+			{Name: "declined", Number: "4580000000000000", Month: "04", Year: "2030"},
+			{Name: "personal", Number: "45801234567899012", Month: "04", Year: "2030"},
+		},
+		TimeZone: "UTC",
+		Rules:    rules,
+	}
+
+	client, err := config.newClient(nil)
+	require.NoError(t, err)
+
+	parsed, err := config.rules()
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+
+	return &testDaemon{
+		Daemon: NewDaemon(client, parsed, NewHistory(filepath.Join(t.TempDir(), "history.jsonl")), DaemonOptions{
+			DryRun:   dryRun,
+			Notifier: notifier,
+			Now:      func() time.Time { return testNow },
+		}),
+		fake:     fake,
+		notifier: notifier,
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	t.Run("should fill a card to max", func(t *testing.T) {
+		daemon := setupTestDaemon(t, testutils.NewFakeCard("11111111-2222-3333-4444-555555555555"), false,
+			RuleConfig{Name: "monthly", Card: "keva", Schedule: "0 9 1 * *", CreditCard: "personal"})
+
+		runs := daemon.RunAll(context.Background())
+
+		assert.Equal(t, []Run{{
+			Rule:       "monthly",
+			Card:       "keva",
+			Time:       testNow,
+			Status:     RunLoaded,
+			Amount:     1000,
+			Cost:       700,
+			CreditCard: "personal",
+			LoadNumber: "10000001",
+		}}, runs)
+
+		card, _ := daemon.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 1000.0, card.Balance)
+		assert.Equal(t, runs, daemon.notifier.runs)
+
+		history, err := daemon.history.Runs()
+		assert.NoError(t, err)
+		assert.Equal(t, runs, history)
+	})
+
+	t.Run("should fill below a threshold within the monthly limit", func(t *testing.T) {
+		keva := testutils.NewFakeCard("11111111-2222-3333-4444-555555555555")
+		keva.Balance = 150
+		keva.MonthlyLoaded = 2800
+
+		daemon := setupTestDaemon(t, keva, false,
+			RuleConfig{Name: "top up", Card: "keva", Schedule: "*/30 * * * *", Below: 200, FillTo: 800, Fallback: true})
+
+		runs := daemon.RunAll(context.Background())
+		assert.Equal(t, RunLoaded, runs[0].Status)
+		assert.Equal(t, 150.0, runs[0].Balance)
+		assert.Equal(t, 200.0, runs[0].Amount)
+		assert.Equal(t, "personal", runs[0].CreditCard)
+
+		// Above the threshold now
+		runs = daemon.RunAll(context.Background())
+		assert.Equal(t, RunSkipped, runs[0].Status)
+		assert.Equal(t, "the balance (350.00) is not below 200.00", runs[0].Message)
+
+		// Skipped runs are recorded, but not notified
+		history, _ := daemon.history.Runs()
+		assert.Len(t, history, 2)
+		assert.Len(t, daemon.notifier.runs, 1)
+	})
+
+	t.Run("should not load on a dry run", func(t *testing.T) {
+		daemon := setupTestDaemon(t, testutils.NewFakeCard("11111111-2222-3333-4444-555555555555"), true,
+			RuleConfig{Name: "top up", Card: "keva", Schedule: "0 * * * *", Below: 200, FillTo: 800})
+
+		runs := daemon.RunAll(context.Background())
+		assert.Equal(t, RunDryRun, runs[0].Status)
+		assert.Equal(t, 800.0, runs[0].Amount)
+		assert.Equal(t, 560.0, runs[0].Cost)
+
+		card, _ := daemon.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 0.0, card.Balance)
+		assert.Len(t, daemon.notifier.runs, 1)
+	})
+
+	t.Run("should notify failures", func(t *testing.T) {
+		daemon := setupTestDaemon(t, testutils.NewFakeCard("11111111-2222-3333-4444-555555555555"), false,
+			RuleConfig{Name: "monthly", Card: "keva", Schedule: "0 9 1 * *", CreditCard: "declined"},
+			RuleConfig{Name: "teamim", Card: "teamim", Schedule: "0 9 1 * *"})
+
+		runs := daemon.RunAll(context.Background())
+
+		assert.Equal(t, RunFailed, runs[0].Status)
+		assert.Equal(t, "declined", runs[0].CreditCard)
+		assert.NotEmpty(t, runs[0].Message)
+
+		// The account has no Teamim card
+		assert.Equal(t, RunFailed, runs[1].Status)
+
+		assert.Len(t, daemon.notifier.runs, 2)
+	})
+}
+
+func TestRulePlan(t *testing.T) {
+	status := &gohever.CardStatus{
+		MaxOnCardAmount:        1000,
+		CurrentBalance:         995.5,
+		RemainingOnCardAmount:  4.5,
+		RemainingMonthlyAmount: 2000,
+	}
+
+	rule, err := newRule(RuleConfig{Name: "monthly", Card: "keva", Schedule: "0 9 1 * *"}, time.UTC)
+	assert.NoError(t, err)
+
+	amount, reason := rule.plan(status)
+	assert.Equal(t, 0.0, amount)
+	assert.Equal(t, "the balance (995.50) is already at the target", reason)
+
+	status.RemainingMonthlyAmount = 0
+	_, reason = rule.plan(status)
+	assert.Equal(t, "the monthly limit was reached", reason)
+
+	_, err = newRule(RuleConfig{Name: "nope", Card: "nope", Schedule: "0 9 1 * *"}, time.UTC)
+	assert.ErrorIs(t, err, gohever.ErrUnknownCardType)
+
+	_, err = newRule(RuleConfig{Name: "nope", Card: "keva", Schedule: "0 9 1 * *", Below: 900, FillTo: 800}, time.UTC)
+	assert.ErrorIs(t, err, errInvalidRule)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_HEVER_PASSWORD", "TestPassword")
+
+	fileName := filepath.Join(t.TempDir(), "autofill.json")
+	os.WriteFile(fileName, []byte(`{
+  "username": "TestUsername",
+  "password": "${TEST_HEVER_PASSWORD}",
+  "rules": [{"name": "monthly", "card": "keva", "schedule": "0 9 1 * *"}]
+}`), 0o600)
+
+	config, err := loadConfig(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "TestPassword", config.Password)
+	assert.Equal(t, defaultHistory, config.HistoryFile)
+
+	rules, err := config.rules()
+	assert.NoError(t, err)
+
+	// Schedules are in Israel time by default
+	next := rules[0].next(time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, time.April, 1, 6, 0, 0, 0, time.UTC), next.UTC())
+
+	config.Rules = nil
+	_, err = config.rules()
+	assert.ErrorIs(t, err, errNoRules)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var body map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+
+	t.Cleanup(server.Close)
+
+	err := newWebhookNotifier(server.URL).Notify(context.Background(), Run{
+		Rule:   "monthly",
+		Card:   "keva",
+		Status: RunDryRun,
+		Amount: 1000,
+		Cost:   700,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dry_run", body["status"])
+	assert.Equal(t, "monthly: would have loaded keva with 1000.00 for 700.00 (dry run)", body["text"])
+}
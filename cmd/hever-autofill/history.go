@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type RunStatus string
+
+const (
+	// The rule's conditions weren't met, nothing was loaded
+	RunSkipped RunStatus = "skipped"
+
+	// The card would have been loaded, if not for the dry run
+	RunDryRun RunStatus = "dry_run"
+
+	RunLoaded RunStatus = "loaded"
+	RunFailed RunStatus = "failed"
+)
+
+// A single evaluation of a rule
+type Run struct {
+	Rule   string    `json:"rule"`
+	Card   string    `json:"card"`
+	Time   time.Time `json:"time"`
+	Status RunStatus `json:"status"`
+
+	// The balance before loading
+	Balance float64 `json:"balance"`
+
+	// The amount loaded, and its cost after the discount
+	Amount float64 `json:"amount,omitempty"`
+	Cost   float64 `json:"cost,omitempty"`
+
+	CreditCard string `json:"credit_card,omitempty"`
+	LoadNumber string `json:"load_number,omitempty"`
+
+	// Why the rule was skipped or failed
+	Message string `json:"message,omitempty"`
+}
+
+// The runs of the daemon, kept in a file as JSON lines
+type History struct {
+	fileName string
+	mu       sync.Mutex
+}
+
+func NewHistory(fileName string) *History {
+	return &History{fileName: fileName}
+}
+
+func (history *History) Append(run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	file, err := os.OpenFile(history.fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Returns all of the runs, oldest first
+func (history *History) Runs() ([]Run, error) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	file, err := os.Open(history.fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var runs []Run
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("unable to parse the history at line %d: %w", line, err)
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, scanner.Err()
+}
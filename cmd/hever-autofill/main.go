@@ -0,0 +1,81 @@
+// Command hever-autofill fills HEVER cards on a schedule, by rules such as "on the 1st, fill Keva
+// to max" or "whenever Teamim drops below 200, load enough to reach 800". See RuleConfig for the
+// rules and Schedule for their schedules.
+//
+// Every run is appended to the history file, and loads, failures and dry runs are posted to the
+// notification webhook.
+//
+// Usage:
+//
+//	hever-autofill -config autofill.json [-dry-run] [-once]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	// The schedules' time zone should be available on systems without tzdata
+	_ "time/tzdata"
+)
+
+func main() {
+	configFile := flag.String("config", "autofill.json", "the config file")
+	dryRun := flag.Bool("dry-run", false, "evaluate the rules without loading anything")
+	once := flag.Bool("once", false, "run every rule once, regardless of their schedules, and exit")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(*configFile, *dryRun, *once, logger); err != nil {
+		logger.Error("autofill failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func run(configFile string, dryRun, once bool, logger *slog.Logger) error {
+	config, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	client, err := config.newClient(logger)
+	if err != nil {
+		return err
+	}
+
+	rules, err := config.rules()
+	if err != nil {
+		return err
+	}
+
+	options := DaemonOptions{
+		DryRun: config.DryRun || dryRun,
+		Logger: logger,
+	}
+
+	if config.Notify.WebhookURL != "" {
+		options.Notifier = newWebhookNotifier(config.Notify.WebhookURL)
+	}
+
+	daemon := NewDaemon(client, rules, NewHistory(config.HistoryFile), options)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if once {
+		for _, run := range daemon.RunAll(ctx) {
+			fmt.Println(run.summary())
+		}
+
+		return nil
+	}
+
+	logger.Info("running", slog.Int("rules", len(rules)), slog.Bool("dry_run", options.DryRun))
+
+	return daemon.Run(ctx)
+}
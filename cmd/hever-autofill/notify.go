@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Reports runs that loaded a card, failed to, or would have in a dry run
+type Notifier interface {
+	Notify(ctx context.Context, run Run) error
+}
+
+// POSTs the runs as JSON to a URL, e.g. a chat webhook
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (notifier *webhookNotifier) Notify(ctx context.Context, run Run) error {
+	body, err := json.Marshal(struct {
+		Run
+		Text string `json:"text"`
+	}{run, run.summary()})
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notifier.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := notifier.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// A human readable summary of the run
+func (run Run) summary() string {
+	switch run.Status {
+	case RunLoaded:
+		return fmt.Sprintf("%s: loaded %s with %.2f for %.2f using %s (load number %s)",
+			run.Rule, run.Card, run.Amount, run.Cost, run.CreditCard, run.LoadNumber)
+	case RunDryRun:
+		return fmt.Sprintf("%s: would have loaded %s with %.2f for %.2f (dry run)",
+			run.Rule, run.Card, run.Amount, run.Cost)
+	case RunFailed:
+		return fmt.Sprintf("%s: failed loading %s: %s", run.Rule, run.Card, run.Message)
+	}
+
+	return fmt.Sprintf("%s: skipped %s: %s", run.Rule, run.Card, run.Message)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+// The minimal amount the site allows loading
+const minimalLoad = 5
+
+type Rule struct {
+	RuleConfig
+
+	cardType gohever.CardType
+	schedule *Schedule
+	location *time.Location
+}
+
+func newRule(config RuleConfig, location *time.Location) (*Rule, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("%w: a rule must have a name", errInvalidRule)
+	}
+
+	var cardType gohever.CardType
+	if err := cardType.UnmarshalText([]byte(config.Card)); err != nil {
+		return nil, fmt.Errorf("%w %q: %w", errInvalidRule, config.Name, err)
+	}
+
+	if config.Below < 0 || config.FillTo < 0 || (config.FillTo > 0 && config.Below > config.FillTo) {
+		return nil, fmt.Errorf("%w %q: below and fill_to should be positive, with below up to fill_to",
+			errInvalidRule, config.Name)
+	}
+
+	schedule, err := ParseSchedule(config.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", errInvalidRule, config.Name, err)
+	}
+
+	return &Rule{
+		RuleConfig: config,
+		cardType:   cardType,
+		schedule:   schedule,
+		location:   location,
+	}, nil
+}
+
+// Returns the next time the rule should be evaluated
+func (rule *Rule) next(after time.Time) time.Time {
+	return rule.schedule.Next(after.In(rule.location))
+}
+
+// Returns the amount to load according to the rule, or zero and the reason for not loading
+func (rule *Rule) plan(status *gohever.CardStatus) (float64, string) {
+	if rule.Below > 0 && status.CurrentBalance >= rule.Below {
+		return 0, fmt.Sprintf("the balance (%.2f) is not below %.2f", status.CurrentBalance, rule.Below)
+	}
+
+	target := float64(status.MaxOnCardAmount)
+	if rule.FillTo > 0 {
+		target = math.Min(target, rule.FillTo)
+	}
+
+	// Loads are in whole amounts, and must fit both the card and the monthly limit
+	amount := math.Floor(math.Min(
+		target-status.CurrentBalance,
+		math.Min(status.RemainingOnCardAmount, status.RemainingMonthlyAmount),
+	))
+
+	if amount < minimalLoad {
+		if status.RemainingMonthlyAmount < minimalLoad {
+			return 0, "the monthly limit was reached"
+		}
+
+		return 0, fmt.Sprintf("the balance (%.2f) is already at the target", status.CurrentBalance)
+	}
+
+	return amount, ""
+}
+
+func (rule *Rule) loadOptions() []gohever.LoadOption {
+	var opts []gohever.LoadOption
+
+	if rule.CreditCard != "" {
+		opts = append(opts, gohever.WithCreditCard(rule.CreditCard))
+	}
+
+	if rule.Fallback {
+		opts = append(opts, gohever.WithCreditCardFallback())
+	}
+
+	return opts
+}
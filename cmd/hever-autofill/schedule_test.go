@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	// A Thursday
+	now := time.Date(2024, time.March, 14, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.March, 14, 12, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.March, 14, 12, 45, 0, 0, time.UTC)},
+		{"0 9 1 * *", time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, time.March, 14, 13, 0, 0, 0, time.UTC)},
+		{"30 8 * * 0", time.Date(2024, time.March, 17, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+
+		// Either the day of month or the day of week
+		{"0 0 20 * 5", time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.spec)
+		assert.NoError(t, err, test.spec)
+		assert.Equal(t, test.next, schedule.Next(now), test.spec)
+	}
+
+	t.Run("should not match impossible dates", func(t *testing.T) {
+		schedule, _ := ParseSchedule("0 0 30 2 *")
+		assert.True(t, schedule.Next(now).IsZero())
+	})
+
+	t.Run("should reject invalid schedules", func(t *testing.T) {
+		for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+			_, err := ParseSchedule(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+}
@@ -0,0 +1,818 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+const (
+	defaultPromptTimeout = 2 * time.Minute
+	historyLength        = 10
+)
+
+var (
+	errPromptTimeout  = errors.New("no reply was received in time")
+	errNoCards        = errors.New("no cards were found for the account")
+	errUnknownCard    = errors.New("unknown card")
+	errNothingToFill  = errors.New("the card is full, or the monthly limit was reached")
+	errInvalidAmount  = errors.New("the amount should be a positive number")
+	errFillAmount     = errors.New("the amount to load should be a whole positive number")
+	errAboveRemaining = errors.New("the amount is above the limits of the card")
+	errFillNotPending = errors.New("this load has expired, use /fill again")
+
+	errReplacementNotPending = errors.New("this replacement has expired, use /replace again")
+)
+
+// Anything that looks like a number, so amounts which can't be loaded aren't taken for card names
+var regexAmountArg = regexp.MustCompile(`^[-+]?[\d.]+([eE][-+]?\d+)?$`)
+
+// An incoming message or button press
+type Update struct {
+	ChatID    int64
+	MessageID int
+	Text      string
+
+	// Set when an inline button was pressed
+	Callback *Callback
+}
+
+type Callback struct {
+	ID   string
+	Data string
+}
+
+// An outgoing message, with optional inline buttons
+type Message struct {
+	ChatID  int64
+	Text    string
+	Buttons []Button
+}
+
+type Button struct {
+	Text string
+	Data string
+}
+
+// Delivers the bot's messages to the chat service. The bot itself knows nothing about Telegram.
+type Transport interface {
+	Send(ctx context.Context, message Message) error
+	AnswerCallback(ctx context.Context, callbackID, text string) error
+	Delete(ctx context.Context, chatID int64, messageID int) error
+}
+
+type BotOptions struct {
+	// Overrides the base URL of the site, e.g. for a staging mirror
+	BaseURL string
+
+	// How long to wait for a reply when asking for a one-time code
+	PromptTimeout time.Duration
+
+	// Allows blocking, unblocking and replacing cards using /block, /unblock and /replace, see
+	// gohever.Config.EnableManagement
+	EnableManagement bool
+
+	Logger *slog.Logger
+}
+
+// The bot logic: commands, prompts and confirmations, for any number of chats
+type Bot struct {
+	transport Transport
+	store     CredentialStore
+	options   BotOptions
+	logger    *slog.Logger
+
+	mu    sync.Mutex
+	chats map[int64]*chat
+}
+
+// The state of a single chat
+type chat struct {
+	client *gohever.Client
+	cards  []gohever.CardInterface
+
+	// The username waiting for a password, after /login
+	pendingLogin string
+
+	// A prompt waiting for the next message of the chat
+	prompt chan string
+
+	pendingFill        *pendingFill
+	pendingReplacement *pendingReplacement
+}
+
+// A load waiting for confirmation
+type pendingFill struct {
+	id     string
+	card   gohever.CardInterface
+	status gohever.CardStatus
+	amount int32
+}
+
+// A replacement waiting for confirmation, as it blocks the card
+type pendingReplacement struct {
+	id   string
+	card gohever.CardInterface
+}
+
+func NewBot(transport Transport, store CredentialStore, options BotOptions) *Bot {
+	if options.PromptTimeout == 0 {
+		options.PromptTimeout = defaultPromptTimeout
+	}
+
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(discard{}, nil))
+	}
+
+	return &Bot{
+		transport: transport,
+		store:     store,
+		options:   options,
+		logger:    logger,
+		chats:     make(map[int64]*chat),
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func (bot *Bot) chat(chatID int64) *chat {
+	c, ok := bot.chats[chatID]
+	if !ok {
+		c = &chat{}
+		bot.chats[chatID] = c
+	}
+
+	return c
+}
+
+// Handles a single update. Handling may block while waiting for a reply from the same chat, so
+// updates should be handled concurrently.
+func (bot *Bot) HandleUpdate(ctx context.Context, update Update) {
+	if update.Callback != nil {
+		bot.handleCallback(ctx, update)
+		return
+	}
+
+	bot.mu.Lock()
+	c := bot.chat(update.ChatID)
+
+	// Replies to prompts and passwords are secrets, so they're deleted from the chat
+	if c.prompt != nil {
+		c.prompt <- strings.TrimSpace(update.Text)
+		c.prompt = nil
+		bot.mu.Unlock()
+
+		bot.transport.Delete(ctx, update.ChatID, update.MessageID)
+		return
+	}
+
+	if username := c.pendingLogin; username != "" {
+		c.pendingLogin = ""
+		bot.mu.Unlock()
+
+		bot.transport.Delete(ctx, update.ChatID, update.MessageID)
+		bot.finishLogin(ctx, update.ChatID, username, strings.TrimSpace(update.Text))
+		return
+	}
+
+	bot.mu.Unlock()
+
+	fields := strings.Fields(update.Text)
+	if len(fields) == 0 {
+		return
+	}
+
+	// Commands may be addressed to the bot, e.g. /status@hever_bot
+	command, _, _ := strings.Cut(fields[0], "@")
+	args := fields[1:]
+
+	var err error
+
+	switch command {
+	case "/start", "/help":
+		err = bot.reply(ctx, update.ChatID, helpText)
+	case "/login":
+		err = bot.handleLogin(ctx, update.ChatID, args)
+	case "/creditcard":
+		bot.transport.Delete(ctx, update.ChatID, update.MessageID)
+		err = bot.handleCreditCard(ctx, update.ChatID, args)
+	case "/logout":
+		err = bot.handleLogout(ctx, update.ChatID)
+	case "/status":
+		err = bot.handleStatus(ctx, update.ChatID)
+	case "/history":
+		err = bot.handleHistory(ctx, update.ChatID, args)
+	case "/estimate":
+		err = bot.handleEstimate(ctx, update.ChatID, args)
+	case "/fill":
+		err = bot.handleFill(ctx, update.ChatID, args)
+	case "/block":
+		err = bot.handleBlock(ctx, update.ChatID, args)
+	case "/unblock":
+		err = bot.handleUnblock(ctx, update.ChatID, args)
+	case "/replace":
+		err = bot.handleReplace(ctx, update.ChatID, args)
+	default:
+		err = bot.reply(ctx, update.ChatID, "Unknown command, see /help")
+	}
+
+	if err != nil {
+		bot.logger.WarnContext(ctx, "command failed",
+			slog.Int64("chat", update.ChatID),
+			slog.String("command", command),
+			slog.String("error", err.Error()))
+
+		bot.reply(ctx, update.ChatID, "Something went wrong: "+err.Error())
+	}
+}
+
+const helpText = `Available commands:
+/login <username> - save your HEVER username, then your password
+/creditcard <number> <month> <year> [name] - save a credit card for loading
+/logout - forget everything about this chat
+/status - the status of your cards
+/history [card] - the recent history of a card
+/estimate <amount> [card] - estimate the cost of loading a card
+/fill [amount] [card] - load a card, up to its limits by default
+/block [card] - block a lost or stolen card right away
+/unblock [card] - unblock a card once it's found
+/replace [card] - order a replacement of a lost or damaged card`
+
+const managementDisabledText = "Managing cards is not enabled for this bot."
+
+func (bot *Bot) reply(ctx context.Context, chatID int64, text string) error {
+	return bot.transport.Send(ctx, Message{ChatID: chatID, Text: text})
+}
+
+// Asks the chat a question, waiting for its next message
+func (bot *Bot) prompt(ctx context.Context, chatID int64, text string) (string, error) {
+	reply := make(chan string, 1)
+
+	bot.mu.Lock()
+	bot.chat(chatID).prompt = reply
+	bot.mu.Unlock()
+
+	if err := bot.reply(ctx, chatID, text); err != nil {
+		return "", err
+	}
+
+	timer := time.NewTimer(bot.options.PromptTimeout)
+	defer timer.Stop()
+
+	select {
+	case answer := <-reply:
+		return answer, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	bot.mu.Lock()
+	if c := bot.chat(chatID); c.prompt == reply {
+		c.prompt = nil
+	}
+	bot.mu.Unlock()
+
+	return "", errPromptTimeout
+}
+
+// Returns the client of the chat, creating it if needed
+func (bot *Bot) client(ctx context.Context, chatID int64) (*gohever.Client, error) {
+	credentials, err := bot.store.Get(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+
+	c := bot.chat(chatID)
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	c.client = gohever.NewClient(gohever.FlavorHvr(), gohever.Config{
+		BaseURL: bot.options.BaseURL,
+		Logger:  bot.logger.With(slog.Int64("chat", chatID)),
+
+		EnableManagement: bot.options.EnableManagement,
+
+		Credentials: func() (gohever.Credentials, error) {
+			if !credentials.OTP {
+				return gohever.Credentials{Username: credentials.Username, Password: credentials.Password}, nil
+			}
+
+			code, err := bot.prompt(ctx, chatID, "Please send the one-time code HEVER has sent you")
+			if err != nil {
+				return gohever.Credentials{}, err
+			}
+
+			return gohever.Credentials{Username: credentials.Username, Password: code}, nil
+		},
+
+		CreditCards: func() ([]gohever.CreditCard, error) {
+			credentials, err := bot.store.Get(chatID)
+			if err != nil {
+				return nil, err
+			}
+
+			var creditCards []gohever.CreditCard
+			for _, creditCard := range credentials.CreditCards {
+				creditCards = append(creditCards, gohever.CreditCard(creditCard))
+			}
+
+			return creditCards, nil
+		},
+	})
+
+	return c.client, nil
+}
+
+// Returns the cards the account of the chat has
+func (bot *Bot) cards(ctx context.Context, chatID int64) ([]gohever.CardInterface, error) {
+	client, err := bot.client(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	bot.mu.Lock()
+	cards := bot.chat(chatID).cards
+	bot.mu.Unlock()
+
+	if cards != nil {
+		return cards, nil
+	}
+
+	cards, err = client.DiscoverCardsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cards) == 0 {
+		return nil, errNoCards
+	}
+
+	bot.mu.Lock()
+	bot.chat(chatID).cards = cards
+	bot.mu.Unlock()
+
+	return cards, nil
+}
+
+// Returns the card named by the argument, or the first card of the account
+func (bot *Bot) card(ctx context.Context, chatID int64, name string) (gohever.CardInterface, error) {
+	cards, err := bot.cards(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return cards[0], nil
+	}
+
+	for _, card := range cards {
+		if card.Type().String() == strings.ToLower(name) {
+			return card, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", errUnknownCard, name)
+}
+
+// Forgets the client of the chat, e.g. when its credentials change
+func (bot *Bot) resetChat(chatID int64) {
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+
+	delete(bot.chats, chatID)
+}
+
+func (bot *Bot) handleLogin(ctx context.Context, chatID int64, args []string) error {
+	if len(args) != 1 {
+		return bot.reply(ctx, chatID, "Usage: /login <username>")
+	}
+
+	bot.resetChat(chatID)
+
+	bot.mu.Lock()
+	bot.chat(chatID).pendingLogin = args[0]
+	bot.mu.Unlock()
+
+	return bot.reply(ctx, chatID, "Now send your password, or /otp to be asked for a one-time code on every login. "+
+		"Your message will be deleted from the chat.")
+}
+
+func (bot *Bot) finishLogin(ctx context.Context, chatID int64, username, password string) {
+	credentials := ChatCredentials{Username: username}
+
+	if password == "/otp" {
+		credentials.OTP = true
+	} else {
+		credentials.Password = password
+	}
+
+	// Keep the saved credit cards
+	if previous, err := bot.store.Get(chatID); err == nil {
+		credentials.CreditCards = previous.CreditCards
+	}
+
+	if err := bot.store.Put(chatID, credentials); err != nil {
+		bot.reply(ctx, chatID, "Something went wrong: "+err.Error())
+		return
+	}
+
+	bot.reply(ctx, chatID, "Your details were saved. Use /creditcard to add a credit card for loading.")
+}
+
+func (bot *Bot) handleCreditCard(ctx context.Context, chatID int64, args []string) error {
+	if len(args) < 3 || len(args) > 4 {
+		return bot.reply(ctx, chatID, "Usage: /creditcard <number> <month> <year> [name]")
+	}
+
+	credentials, err := bot.store.Get(chatID)
+	if errors.Is(err, errNoCredentials) {
+		return bot.reply(ctx, chatID, "Please /login first")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	creditCard := ChatCreditCard{Number: args[0], Month: args[1], Year: args[2]}
+	if len(args) == 4 {
+		creditCard.Name = args[3]
+	}
+
+	credentials.CreditCards = append(credentials.CreditCards, creditCard)
+
+	if err := bot.store.Put(chatID, *credentials); err != nil {
+		return err
+	}
+
+	return bot.reply(ctx, chatID, "The credit card "+gohever.MaskCardNumber(creditCard.Number)+" was saved.")
+}
+
+func (bot *Bot) handleLogout(ctx context.Context, chatID int64) error {
+	bot.resetChat(chatID)
+
+	if err := bot.store.Delete(chatID); err != nil {
+		return err
+	}
+
+	return bot.reply(ctx, chatID, "All of your details were deleted.")
+}
+
+func (bot *Bot) handleStatus(ctx context.Context, chatID int64) error {
+	cards, err := bot.cards(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	for _, card := range cards {
+		status, err := card.GetStatusContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&text, "%s\nBalance: %s\nLeft this month: %s\nRoom on card: %s\n",
+			cardTitle(card.Type()),
+			formatAmount(status.CurrentBalance),
+			formatAmount(status.RemainingMonthlyAmount),
+			formatAmount(status.RemainingOnCardAmount))
+
+		text.WriteString("\n")
+	}
+
+	return bot.reply(ctx, chatID, strings.TrimSpace(text.String()))
+}
+
+func (bot *Bot) handleHistory(ctx context.Context, chatID int64, args []string) error {
+	card, err := bot.card(ctx, chatID, argAt(args, 0))
+	if err != nil {
+		return err
+	}
+
+	history, err := card.GetHistoryContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(*history) == 0 {
+		return bot.reply(ctx, chatID, "No recent history for "+cardTitle(card.Type()))
+	}
+
+	items := *history
+	if len(items) > historyLength {
+		items = items[len(items)-historyLength:]
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n", cardTitle(card.Type()))
+
+	for _, item := range items {
+		sign, name := "-", item.BusinessName
+		if item.ActionType == gohever.ActionLoad {
+			sign, name = "+", "Load"
+		}
+
+		fmt.Fprintf(&text, "%s %s%s %s\n", item.Date, sign, formatAmount(item.Amount), strings.TrimSpace(name))
+	}
+
+	return bot.reply(ctx, chatID, strings.TrimSpace(text.String()))
+}
+
+func (bot *Bot) handleEstimate(ctx context.Context, chatID int64, args []string) error {
+	if len(args) < 1 {
+		return bot.reply(ctx, chatID, "Usage: /estimate <amount> [card]")
+	}
+
+	amount, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || amount <= 0 {
+		return errInvalidAmount
+	}
+
+	card, err := bot.card(ctx, chatID, argAt(args, 1))
+	if err != nil {
+		return err
+	}
+
+	status, err := card.GetStatusContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	estimate, err := status.Estimate(amount)
+	if err != nil {
+		return err
+	}
+
+	return bot.reply(ctx, chatID, formatEstimate(card.Type(), estimate))
+}
+
+// Both arguments are optional, and the amount comes first like in /estimate, e.g. "/fill 300" or
+// "/fill 300 teamim". A card alone fills it up, e.g. "/fill teamim".
+func (bot *Bot) handleFill(ctx context.Context, chatID int64, args []string) error {
+	amountArg, cardName := argAt(args, 0), argAt(args, 1)
+	if amountArg != "" && !regexAmountArg.MatchString(amountArg) {
+		amountArg, cardName = cardName, amountArg
+	}
+
+	// Only whole amounts are loaded, so the estimate is of what is actually loaded
+	var requested int64
+	if amountArg != "" {
+		var err error
+		if requested, err = strconv.ParseInt(amountArg, 10, 32); err != nil || requested <= 0 {
+			return errFillAmount
+		}
+	}
+
+	card, err := bot.card(ctx, chatID, cardName)
+	if err != nil {
+		return err
+	}
+
+	status, err := card.GetStatusContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Fill up the card by default
+	remaining := math.Floor(math.Min(status.RemainingOnCardAmount, status.RemainingMonthlyAmount))
+	if remaining <= 0 {
+		return errNothingToFill
+	}
+
+	amount := remaining
+	if requested > 0 {
+		amount = float64(requested)
+	}
+
+	if amount > remaining {
+		return fmt.Errorf("%w, up to %s can be loaded", errAboveRemaining, formatAmount(remaining))
+	}
+
+	estimate, err := status.Estimate(amount)
+	if err != nil {
+		return err
+	}
+
+	fill := &pendingFill{
+		id:     randomID(),
+		card:   card,
+		status: *status,
+		amount: int32(amount),
+	}
+
+	bot.mu.Lock()
+	bot.chat(chatID).pendingFill = fill
+	bot.mu.Unlock()
+
+	return bot.transport.Send(ctx, Message{
+		ChatID: chatID,
+		Text:   formatEstimate(card.Type(), estimate) + "\n\nLoad the card?",
+		Buttons: []Button{
+			{Text: "Load " + formatAmount(amount), Data: "fill:" + fill.id},
+			{Text: "Cancel", Data: "cancel:" + fill.id},
+		},
+	})
+}
+
+// Blocking is not confirmed, so a stolen card can be frozen as fast as possible
+func (bot *Bot) handleBlock(ctx context.Context, chatID int64, args []string) error {
+	if !bot.options.EnableManagement {
+		return bot.reply(ctx, chatID, managementDisabledText)
+	}
+
+	card, err := bot.card(ctx, chatID, argAt(args, 0))
+	if err != nil {
+		return err
+	}
+
+	result, err := card.BlockContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return bot.reply(ctx, chatID, formatManageResult(card.Type(), result, "was blocked"))
+}
+
+func (bot *Bot) handleUnblock(ctx context.Context, chatID int64, args []string) error {
+	if !bot.options.EnableManagement {
+		return bot.reply(ctx, chatID, managementDisabledText)
+	}
+
+	card, err := bot.card(ctx, chatID, argAt(args, 0))
+	if err != nil {
+		return err
+	}
+
+	result, err := card.UnblockContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return bot.reply(ctx, chatID, formatManageResult(card.Type(), result, "was unblocked"))
+}
+
+func (bot *Bot) handleReplace(ctx context.Context, chatID int64, args []string) error {
+	if !bot.options.EnableManagement {
+		return bot.reply(ctx, chatID, managementDisabledText)
+	}
+
+	card, err := bot.card(ctx, chatID, argAt(args, 0))
+	if err != nil {
+		return err
+	}
+
+	replacement := &pendingReplacement{
+		id:   randomID(),
+		card: card,
+	}
+
+	bot.mu.Lock()
+	bot.chat(chatID).pendingReplacement = replacement
+	bot.mu.Unlock()
+
+	return bot.transport.Send(ctx, Message{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("Order a replacement of %s? The current card will be blocked.", cardTitle(card.Type())),
+		Buttons: []Button{
+			{Text: "Order a replacement", Data: "replace:" + replacement.id},
+			{Text: "Cancel", Data: "keep:" + replacement.id},
+		},
+	})
+}
+
+func (bot *Bot) handleCallback(ctx context.Context, update Update) {
+	action, id, _ := strings.Cut(update.Callback.Data, ":")
+
+	if action == "replace" || action == "keep" {
+		bot.handleReplaceCallback(ctx, update, action, id)
+		return
+	}
+
+	// Take the pending load, so pressing the button twice won't load twice
+	bot.mu.Lock()
+	c := bot.chat(update.ChatID)
+	fill := c.pendingFill
+	if fill != nil && fill.id == id {
+		c.pendingFill = nil
+	} else {
+		fill = nil
+	}
+	bot.mu.Unlock()
+
+	if fill == nil {
+		bot.transport.AnswerCallback(ctx, update.Callback.ID, errFillNotPending.Error())
+		return
+	}
+
+	if action != "fill" {
+		bot.transport.AnswerCallback(ctx, update.Callback.ID, "Cancelled")
+		bot.reply(ctx, update.ChatID, "The load was cancelled.")
+		return
+	}
+
+	bot.transport.AnswerCallback(ctx, update.Callback.ID, "Loading...")
+
+	result, err := fill.card.LoadContext(ctx, fill.status, fill.amount, gohever.WithCreditCardFallback())
+	if err != nil {
+		bot.reply(ctx, update.ChatID, "Something went wrong: "+err.Error())
+		return
+	}
+
+	if result.Status != gohever.StatusSuccess {
+		bot.reply(ctx, update.ChatID, "The load failed: "+result.RawMessage)
+		return
+	}
+
+	bot.reply(ctx, update.ChatID, fmt.Sprintf("%s was loaded with %s using %s. Load number: %s",
+		cardTitle(fill.card.Type()), formatAmount(float64(fill.amount)), result.CreditCard, result.LoadNumber))
+}
+
+func (bot *Bot) handleReplaceCallback(ctx context.Context, update Update, action, id string) {
+	bot.mu.Lock()
+	c := bot.chat(update.ChatID)
+	replacement := c.pendingReplacement
+	if replacement != nil && replacement.id == id {
+		c.pendingReplacement = nil
+	} else {
+		replacement = nil
+	}
+	bot.mu.Unlock()
+
+	if replacement == nil {
+		bot.transport.AnswerCallback(ctx, update.Callback.ID, errReplacementNotPending.Error())
+		return
+	}
+
+	if action != "replace" {
+		bot.transport.AnswerCallback(ctx, update.Callback.ID, "Cancelled")
+		bot.reply(ctx, update.ChatID, "The replacement was cancelled.")
+		return
+	}
+
+	bot.transport.AnswerCallback(ctx, update.Callback.ID, "Ordering...")
+
+	result, err := replacement.card.RequestReplacementContext(ctx)
+	if err != nil {
+		bot.reply(ctx, update.ChatID, "Something went wrong: "+err.Error())
+		return
+	}
+
+	bot.reply(ctx, update.ChatID, formatManageResult(replacement.card.Type(), result, "will be replaced"))
+}
+
+func cardTitle(cardType gohever.CardType) string {
+	name := cardType.String()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func formatManageResult(cardType gohever.CardType, result *gohever.ManageResult, done string) string {
+	if result.Outcome != gohever.OutcomeDone {
+		return "The request failed: " + result.RawMessage
+	}
+
+	return fmt.Sprintf("%s %s. Reference number: %s", cardTitle(cardType), done, result.ReferenceNumber)
+}
+
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f₪", amount)
+}
+
+func formatEstimate(cardType gohever.CardType, estimate *gohever.CardEstimate) string {
+	return fmt.Sprintf("%s\nLoading %s will cost %s (%s off)",
+		cardTitle(cardType),
+		formatAmount(estimate.Total),
+		formatAmount(estimate.TotalFactored),
+		formatAmount(estimate.Total-estimate.TotalFactored))
+}
+
+func argAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+
+	return ""
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
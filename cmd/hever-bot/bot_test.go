@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+const testChatID = 42
+
+// A Transport recording what the bot sends
+type fakeTransport struct {
+	mu      sync.Mutex
+	answers []string
+	deleted []int
+
+	sent chan Message
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{sent: make(chan Message, 100)}
+}
+
+func (transport *fakeTransport) Send(ctx context.Context, message Message) error {
+	transport.sent <- message
+	return nil
+}
+
+func (transport *fakeTransport) AnswerCallback(ctx context.Context, callbackID, text string) error {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	transport.answers = append(transport.answers, text)
+	return nil
+}
+
+func (transport *fakeTransport) Delete(ctx context.Context, chatID int64, messageID int) error {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	transport.deleted = append(transport.deleted, messageID)
+	return nil
+}
+
+// Returns the next message sent by the bot
+func (transport *fakeTransport) next(t *testing.T) Message {
+	t.Helper()
+
+	select {
+	case message := <-transport.sent:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("the bot didn't send a message")
+		return Message{}
+	}
+}
+
+type testBot struct {
+	*Bot
+	transport *fakeTransport
+	fake      *testutils.FakeHever
+	messageID int
+}
+
+func setupTestBot(t *testing.T) *testBot {
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username:            "TestUsername",
+		Password:            "TestPassword",
+		DeclinedCreditCards: []string{"4580000000000000"},
+	}).SetupTest(t)
+
+	store, err := newEncryptedFileStore(filepath.Join(t.TempDir(), "credentials.json"), testKey)
+	require.NoError(t, err)
+
+	transport := newFakeTransport()
+
+	return &testBot{
+		Bot:       NewBot(transport, store, BotOptions{BaseURL: fake.URL(), PromptTimeout: 5 * time.Second}),
+		transport: transport,
+		fake:      fake,
+	}
+}
+
+// Sends a message to the bot, returning its ID
+func (bot *testBot) send(text string) int {
+	bot.messageID++
+	bot.HandleUpdate(context.Background(), Update{ChatID: testChatID, MessageID: bot.messageID, Text: text})
+
+	return bot.messageID
+}
+
+func (bot *testBot) press(data string) {
+	bot.HandleUpdate(context.Background(), Update{
+		ChatID:   testChatID,
+		Callback: &Callback{ID: "callback", Data: data},
+	})
+}
+
+func (bot *testBot) login(t *testing.T) {
+	bot.send("/login TestUsername")
+	bot.transport.next(t)
+
+	bot.send("TestPassword")
+	bot.transport.next(t)
+
+	bot.send("/creditcard 4580000000000000 04 2030 declined")
+	bot.transport.next(t)
+
+	bot.send("/creditcard 45801234567899012 04 2030 personal")
+	bot.transport.next(t)
+}
+
+func TestBot(t *testing.T) {
+	t.Run("should ask to login first", func(t *testing.T) {
+		bot := setupTestBot(t)
+
+		bot.send("/status")
+		assert.Contains(t, bot.transport.next(t).Text, errNoCredentials.Error())
+	})
+
+	t.Run("should delete secrets from the chat", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		// The password and both credit cards
+		assert.Equal(t, []int{2, 3, 4}, bot.transport.deleted)
+
+		credentials, err := bot.store.Get(testChatID)
+		assert.NoError(t, err)
+		assert.Equal(t, "TestPassword", credentials.Password)
+		assert.Len(t, credentials.CreditCards, 2)
+	})
+
+	t.Run("should show the status of the available cards", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/status")
+		text := bot.transport.next(t).Text
+
+		assert.Contains(t, text, "Keva\nBalance: 0.00₪\nLeft this month: 3000.00₪\nRoom on card: 1000.00₪")
+		assert.Contains(t, text, "Teamim")
+		assert.Equal(t, 1, bot.fake.Logins())
+	})
+
+	t.Run("should show the history of a card", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/history teamim")
+		assert.Equal(t, "No recent history for Teamim", bot.transport.next(t).Text)
+
+		bot.send("/fill 100 teamim")
+		bot.press(bot.transport.next(t).Buttons[0].Data)
+		bot.transport.next(t)
+
+		assert.NoError(t, bot.fake.Purchase(testutils.FakeCardTeamim, "Pizza", 52.5))
+
+		bot.send("/history teamim")
+		assert.Contains(t, bot.transport.next(t).Text, "-52.50₪ Pizza")
+
+		bot.send("/history sheli")
+		assert.Contains(t, bot.transport.next(t).Text, "unknown card: sheli")
+	})
+
+	t.Run("should estimate a load", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/estimate 100")
+		assert.Equal(t, "Keva\nLoading 100.00₪ will cost 70.00₪ (30.00₪ off)", bot.transport.next(t).Text)
+
+		bot.send("/estimate nope")
+		assert.Contains(t, bot.transport.next(t).Text, errInvalidAmount.Error())
+	})
+
+	t.Run("should load a card only once confirmed", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/fill 400 keva")
+		message := bot.transport.next(t)
+
+		assert.Contains(t, message.Text, "Loading 400.00₪ will cost 280.00₪")
+		require.Len(t, message.Buttons, 2)
+
+		card, _ := bot.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 0.0, card.Balance)
+
+		bot.press(message.Buttons[0].Data)
+		assert.Contains(t, bot.transport.next(t).Text, "Keva was loaded with 400.00₪ using personal")
+
+		card, _ = bot.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 400.0, card.Balance)
+
+		// Pressing again won't load again
+		bot.press(message.Buttons[0].Data)
+		assert.Equal(t, errFillNotPending.Error(), bot.transport.answers[len(bot.transport.answers)-1])
+
+		card, _ = bot.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 400.0, card.Balance)
+	})
+
+	t.Run("should fill a card up to its limits by default", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/fill")
+		message := bot.transport.next(t)
+		assert.Contains(t, message.Text, "Loading 1000.00₪")
+
+		bot.press(message.Buttons[1].Data)
+		assert.Equal(t, "The load was cancelled.", bot.transport.next(t).Text)
+
+		card, _ := bot.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 0.0, card.Balance)
+	})
+
+	t.Run("should take the amount or the card as the first argument", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/fill 300")
+		message := bot.transport.next(t)
+		assert.Contains(t, message.Text, "Keva\nLoading 300.00₪ will cost 210.00₪")
+
+		bot.press(message.Buttons[0].Data)
+		assert.Contains(t, bot.transport.next(t).Text, "Keva was loaded with 300.00₪")
+
+		bot.send("/fill teamim")
+		assert.Contains(t, bot.transport.next(t).Text, "Teamim\nLoading 1000.00₪")
+
+		// The old order still works
+		bot.send("/fill teamim 200")
+		assert.Contains(t, bot.transport.next(t).Text, "Teamim\nLoading 200.00₪")
+	})
+
+	t.Run("should only fill whole amounts within the limits", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		for _, amount := range []string{"300.7", "3e9", "0", "-5", "99999999999"} {
+			bot.send("/fill " + amount)
+			assert.Contains(t, bot.transport.next(t).Text, errFillAmount.Error(), amount)
+		}
+
+		bot.send("/fill 1001")
+		assert.Contains(t, bot.transport.next(t).Text, errAboveRemaining.Error()+", up to 1000.00₪ can be loaded")
+
+		card, _ := bot.fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 0.0, card.Balance)
+	})
+
+	t.Run("should block and unblock a card right away", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.options.EnableManagement = true
+		bot.login(t)
+
+		bot.send("/block keva")
+		assert.Contains(t, bot.transport.next(t).Text, "Keva was blocked. Reference number: ")
+
+		card, _ := bot.fake.Card(testutils.FakeCardKeva)
+		assert.True(t, card.Blocked)
+
+		bot.send("/block keva")
+		assert.Equal(t, "The request failed: הכרטיס כבר חסום", bot.transport.next(t).Text)
+
+		bot.send("/unblock keva")
+		assert.Contains(t, bot.transport.next(t).Text, "Keva was unblocked. Reference number: ")
+
+		card, _ = bot.fake.Card(testutils.FakeCardKeva)
+		assert.False(t, card.Blocked)
+	})
+
+	t.Run("should not manage a card unless enabled", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		for _, command := range []string{"/block teamim", "/unblock teamim", "/replace teamim"} {
+			bot.send(command)
+			assert.Equal(t, managementDisabledText, bot.transport.next(t).Text)
+		}
+
+		card, _ := bot.fake.Card(testutils.FakeCardTeamim)
+		assert.False(t, card.Blocked)
+		assert.Equal(t, 0, card.Replacements)
+	})
+
+	t.Run("should replace a card only once confirmed", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.options.EnableManagement = true
+		bot.login(t)
+
+		bot.send("/replace teamim")
+		message := bot.transport.next(t)
+		require.Len(t, message.Buttons, 2)
+
+		bot.press(message.Buttons[1].Data)
+		assert.Equal(t, "The replacement was cancelled.", bot.transport.next(t).Text)
+
+		bot.send("/replace teamim")
+		message = bot.transport.next(t)
+
+		bot.press(message.Buttons[0].Data)
+		assert.Contains(t, bot.transport.next(t).Text, "Teamim will be replaced. Reference number: ")
+
+		card, _ := bot.fake.Card(testutils.FakeCardTeamim)
+		assert.Equal(t, 1, card.Replacements)
+
+		// Pressing again won't order another replacement
+		bot.press(message.Buttons[0].Data)
+		assert.Equal(t, errReplacementNotPending.Error(), bot.transport.answers[len(bot.transport.answers)-1])
+
+		card, _ = bot.fake.Card(testutils.FakeCardTeamim)
+		assert.Equal(t, 1, card.Replacements)
+	})
+
+	t.Run("should prompt for a one-time code", func(t *testing.T) {
+		bot := setupTestBot(t)
+
+		bot.send("/login TestUsername")
+		bot.transport.next(t)
+		bot.send("/otp")
+		bot.transport.next(t)
+
+		done := make(chan struct{})
+		go func() {
+			bot.send("/status")
+			close(done)
+		}()
+
+		assert.True(t, strings.HasPrefix(bot.transport.next(t).Text, "Please send the one-time code"))
+
+		bot.HandleUpdate(context.Background(), Update{ChatID: testChatID, MessageID: 100, Text: "TestPassword"})
+		<-done
+
+		assert.Contains(t, bot.transport.next(t).Text, "Keva\nBalance")
+		assert.Contains(t, bot.transport.deleted, 100)
+	})
+
+	t.Run("should forget the chat on logout", func(t *testing.T) {
+		bot := setupTestBot(t)
+		bot.login(t)
+
+		bot.send("/logout")
+		bot.transport.next(t)
+
+		_, err := bot.store.Get(testChatID)
+		assert.ErrorIs(t, err, errNoCredentials)
+	})
+}
@@ -0,0 +1,67 @@
+// Command hever-bot is a Telegram bot for checking and loading HEVER cards from a chat.
+//
+// The bot token is read from TELEGRAM_TOKEN. The credentials of each chat are encrypted with the
+// key in BOT_ENCRYPTION_KEY, a base64 encoded 32 bytes key (e.g. `openssl rand -base64 32`).
+//
+// Usage:
+//
+//	hever-bot -store credentials.json
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	storeFile := flag.String("store", "credentials.json", "the file to keep the encrypted credentials in")
+	baseURL := flag.String("base-url", "", "overrides the base URL of the site")
+	enableManagement := flag.Bool("enable-management", false, "allows blocking, unblocking and replacing cards")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(*storeFile, *baseURL, *enableManagement, logger); err != nil {
+		logger.Error("bot failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func run(storeFile, baseURL string, enableManagement bool, logger *slog.Logger) error {
+	token := os.Getenv("TELEGRAM_TOKEN")
+	if token == "" {
+		return errors.New("TELEGRAM_TOKEN is not set")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("BOT_ENCRYPTION_KEY"))
+	if err != nil {
+		return errors.New("BOT_ENCRYPTION_KEY is not valid base64")
+	}
+
+	store, err := newEncryptedFileStore(storeFile, key)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	transport := NewTelegramTransport(token, logger)
+	bot := NewBot(transport, store, BotOptions{
+		BaseURL:          baseURL,
+		EnableManagement: enableManagement,
+		Logger:           logger,
+	})
+
+	logger.Info("polling for updates")
+
+	return transport.Run(ctx, func(update Update) {
+		bot.HandleUpdate(ctx, update)
+	})
+}
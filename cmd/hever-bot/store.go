@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+var (
+	errNoCredentials    = errors.New("no credentials were saved for the chat")
+	errInvalidKeyLength = errors.New("the encryption key should be 32 bytes long")
+)
+
+// The details saved for a chat
+type ChatCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+
+	// Ask for a one-time code on every login, rather than saving the password
+	OTP bool `json:"otp,omitempty"`
+
+	CreditCards []ChatCreditCard `json:"credit_cards,omitempty"`
+}
+
+type ChatCreditCard struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+	Month  string `json:"month"`
+	Year   string `json:"year"`
+}
+
+// Stores the credentials of every chat
+type CredentialStore interface {
+	// Returns errNoCredentials when nothing was saved for the chat
+	Get(chatID int64) (*ChatCredentials, error)
+	Put(chatID int64, credentials ChatCredentials) error
+	Delete(chatID int64) error
+}
+
+// A CredentialStore keeping the credentials in a file, each chat encrypted on its own using
+// AES-GCM. The chat ID is authenticated along, so entries can't be swapped between chats.
+type encryptedFileStore struct {
+	fileName string
+	aead     cipher.AEAD
+
+	mu sync.Mutex
+}
+
+func newEncryptedFileStore(fileName string, key []byte) (*encryptedFileStore, error) {
+	if len(key) != 32 {
+		return nil, errInvalidKeyLength
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptedFileStore{
+		fileName: fileName,
+		aead:     aead,
+	}, nil
+}
+
+func (store *encryptedFileStore) read() (map[string]string, error) {
+	entries := make(map[string]string)
+
+	data, err := os.ReadFile(store.fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse the credential store: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Writes the entries to a temporary file first, so a crash won't leave a broken store behind
+func (store *encryptedFileStore) write(entries map[string]string) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(store.fileName), ".credentials-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), store.fileName)
+}
+
+func (store *encryptedFileStore) Get(chatID int64) (*ChatCredentials, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entries, err := store.read()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := entries[strconv.FormatInt(chatID, 10)]
+	if !ok {
+		return nil, errNoCredentials
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(entry)
+	if err != nil || len(sealed) < store.aead.NonceSize() {
+		return nil, fmt.Errorf("corrupted credentials for chat %d", chatID)
+	}
+
+	nonce, ciphertext := sealed[:store.aead.NonceSize()], sealed[store.aead.NonceSize():]
+
+	data, err := store.aead.Open(nil, nonce, ciphertext, []byte(strconv.FormatInt(chatID, 10)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the credentials for chat %d: %w", chatID, err)
+	}
+
+	var credentials ChatCredentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, err
+	}
+
+	return &credentials, nil
+}
+
+func (store *encryptedFileStore) Put(chatID int64, credentials ChatCredentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, store.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	key := strconv.FormatInt(chatID, 10)
+	sealed := store.aead.Seal(nonce, nonce, data, []byte(key))
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entries, err := store.read()
+	if err != nil {
+		return err
+	}
+
+	entries[key] = base64.StdEncoding.EncodeToString(sealed)
+
+	return store.write(entries)
+}
+
+func (store *encryptedFileStore) Delete(chatID int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entries, err := store.read()
+	if err != nil {
+		return err
+	}
+
+	delete(entries, strconv.FormatInt(chatID, 10))
+
+	return store.write(entries)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte(strings.Repeat("k", 32))
+
+func TestEncryptedFileStore(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "credentials.json")
+
+	store, err := newEncryptedFileStore(fileName, testKey)
+	assert.NoError(t, err)
+
+	_, err = store.Get(1)
+	assert.ErrorIs(t, err, errNoCredentials)
+
+	credentials := ChatCredentials{
+		Username:    "TestUsername",
+		Password:    "TestPassword",
+		CreditCards: []ChatCreditCard{{Name: "personal", Number: "45801234567899012", Month: "04", Year: "2030"}},
+	}
+
+	assert.NoError(t, store.Put(1, credentials))
+
+	got, err := store.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, credentials, *got)
+
+	// Nothing is kept in plain text
+	data, _ := os.ReadFile(fileName)
+	assert.NotContains(t, string(data), "TestPassword")
+	assert.NotContains(t, string(data), "45801234567899012")
+
+	t.Run("should not decrypt with another key", func(t *testing.T) {
+		other, _ := newEncryptedFileStore(fileName, []byte(strings.Repeat("x", 32)))
+
+		_, err := other.Get(1)
+		assert.ErrorContains(t, err, "unable to decrypt")
+	})
+
+	t.Run("should not decrypt an entry moved to another chat", func(t *testing.T) {
+		data, _ := os.ReadFile(fileName)
+		os.WriteFile(fileName, []byte(strings.Replace(string(data), `"1"`, `"2"`, 1)), 0o600)
+
+		_, err := store.Get(2)
+		assert.ErrorContains(t, err, "unable to decrypt")
+	})
+
+	t.Run("should delete the credentials", func(t *testing.T) {
+		assert.NoError(t, store.Put(1, credentials))
+		assert.NoError(t, store.Delete(1))
+
+		_, err := store.Get(1)
+		assert.ErrorIs(t, err, errNoCredentials)
+	})
+
+	_, err = newEncryptedFileStore(fileName, []byte("short"))
+	assert.ErrorIs(t, err, errInvalidKeyLength)
+}
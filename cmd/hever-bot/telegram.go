@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTelegramAPIURL = "https://api.telegram.org"
+	pollTimeout           = 30 * time.Second
+)
+
+// A Transport over the Telegram Bot API, using long polling
+type TelegramTransport struct {
+	// The API URL, overridable for tests
+	APIURL string
+
+	token  string
+	client *http.Client
+	logger *slog.Logger
+}
+
+func NewTelegramTransport(token string, logger *slog.Logger) *TelegramTransport {
+	return &TelegramTransport{
+		APIURL: defaultTelegramAPIURL,
+		token:  token,
+		client: &http.Client{Timeout: pollTimeout + 10*time.Second},
+		logger: logger,
+	}
+}
+
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+
+	Message *struct {
+		MessageID int    `json:"message_id"`
+		Text      string `json:"text"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+
+	CallbackQuery *struct {
+		ID      string `json:"id"`
+		Data    string `json:"data"`
+		Message struct {
+			MessageID int `json:"message_id"`
+			Chat      struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"callback_query"`
+}
+
+type inlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+func (transport *TelegramTransport) call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(transport.APIURL, "/") + "/bot" + transport.token + "/" + method
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := transport.client.Do(req)
+	if err != nil {
+		// Don't leak the token, which is part of the URL
+		var urlErr interface{ Unwrap() error }
+		if errors.As(err, &urlErr) {
+			err = urlErr.Unwrap()
+		}
+
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+
+	defer resp.Body.Close()
+
+	var response telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+
+	if !response.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, response.Description)
+	}
+
+	if result != nil {
+		return json.Unmarshal(response.Result, result)
+	}
+
+	return nil
+}
+
+func (transport *TelegramTransport) Send(ctx context.Context, message Message) error {
+	params := map[string]any{
+		"chat_id": message.ChatID,
+		"text":    message.Text,
+	}
+
+	if len(message.Buttons) > 0 {
+		var row []inlineKeyboardButton
+		for _, button := range message.Buttons {
+			row = append(row, inlineKeyboardButton{Text: button.Text, CallbackData: button.Data})
+		}
+
+		params["reply_markup"] = map[string]any{
+			"inline_keyboard": [][]inlineKeyboardButton{row},
+		}
+	}
+
+	return transport.call(ctx, "sendMessage", params, nil)
+}
+
+func (transport *TelegramTransport) AnswerCallback(ctx context.Context, callbackID, text string) error {
+	return transport.call(ctx, "answerCallbackQuery", map[string]any{
+		"callback_query_id": callbackID,
+		"text":              text,
+	}, nil)
+}
+
+func (transport *TelegramTransport) Delete(ctx context.Context, chatID int64, messageID int) error {
+	return transport.call(ctx, "deleteMessage", map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+	}, nil)
+}
+
+// Polls for updates until the context is done, passing each one to handle on its own goroutine
+func (transport *TelegramTransport) Run(ctx context.Context, handle func(Update)) error {
+	var offset int64
+
+	for {
+		var updates []telegramUpdate
+
+		err := transport.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         int(pollTimeout.Seconds()),
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			transport.logger.WarnContext(ctx, "polling for updates failed", slog.String("error", err.Error()))
+
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return nil
+			}
+
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+
+			if u, ok := toUpdate(update); ok {
+				go handle(u)
+			}
+		}
+	}
+}
+
+func toUpdate(update telegramUpdate) (Update, bool) {
+	switch {
+	case update.CallbackQuery != nil:
+		return Update{
+			ChatID:    update.CallbackQuery.Message.Chat.ID,
+			MessageID: update.CallbackQuery.Message.MessageID,
+			Callback: &Callback{
+				ID:   update.CallbackQuery.ID,
+				Data: update.CallbackQuery.Data,
+			},
+		}, true
+
+	case update.Message != nil && update.Message.Text != "":
+		return Update{
+			ChatID:    update.Message.Chat.ID,
+			MessageID: update.Message.MessageID,
+			Text:      update.Message.Text,
+		}, true
+	}
+
+	return Update{}, false
+}
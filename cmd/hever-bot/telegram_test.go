@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramTransport(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var sentMessage map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		calls = append(calls, r.URL.Path)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/botTOKEN/getUpdates":
+			var params struct{ Offset int64 }
+			json.Unmarshal(body, &params)
+
+			if params.Offset == 0 {
+				io.WriteString(w, `{"ok": true, "result": [
+					{"update_id": 7, "message": {"message_id": 1, "text": "/status", "chat": {"id": 42}}},
+					{"update_id": 8, "callback_query": {"id": "cb", "data": "fill:abc", "message": {"message_id": 2, "chat": {"id": 42}}}}
+				]}`)
+				return
+			}
+
+			// Long polling with nothing new
+			<-r.Context().Done()
+
+		case "/botTOKEN/sendMessage":
+			mu.Lock()
+			json.Unmarshal(body, &sentMessage)
+			mu.Unlock()
+
+			io.WriteString(w, `{"ok": true, "result": {}}`)
+
+		default:
+			io.WriteString(w, `{"ok": false, "description": "Bad Request: message can't be deleted"}`)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	transport := NewTelegramTransport("TOKEN", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	transport.APIURL = server.URL
+
+	t.Run("should poll for updates", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		updates := make(chan Update, 2)
+
+		go transport.Run(ctx, func(update Update) { updates <- update })
+
+		var got []Update
+		for len(got) < 2 {
+			select {
+			case update := <-updates:
+				got = append(got, update)
+			case <-time.After(5 * time.Second):
+				t.Fatal("no updates were received")
+			}
+		}
+
+		cancel()
+
+		assert.ElementsMatch(t, []Update{
+			{ChatID: 42, MessageID: 1, Text: "/status"},
+			{ChatID: 42, MessageID: 2, Callback: &Callback{ID: "cb", Data: "fill:abc"}},
+		}, got)
+	})
+
+	t.Run("should send messages with buttons", func(t *testing.T) {
+		err := transport.Send(context.Background(), Message{
+			ChatID:  42,
+			Text:    "Load the card?",
+			Buttons: []Button{{Text: "Load", Data: "fill:abc"}},
+		})
+
+		assert.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, "Load the card?", sentMessage["text"])
+		assert.Equal(t, map[string]any{
+			"inline_keyboard": []any{[]any{map[string]any{"text": "Load", "callback_data": "fill:abc"}}},
+		}, sentMessage["reply_markup"])
+	})
+
+	t.Run("should report API errors", func(t *testing.T) {
+		err := transport.Delete(context.Background(), 42, 1)
+		assert.EqualError(t, err, "telegram deleteMessage failed: Bad Request: message can't be deleted")
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/archive"
+)
+
+const defaultArchive = "hever.db"
+
+func runArchive(args []string, stdout io.Writer, getenv func(string) string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("archive "+args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	fileName := flags.String("db", defaultArchive, "the archive database")
+	cardName := flags.String("card", "", "only query the given card")
+	since := flags.String("since", "", "only query the history since the given date (YYYY-MM-DD)")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	var opts []archive.QueryOption
+
+	if *cardName != "" {
+		var cardType gohever.CardType
+		if err := cardType.UnmarshalText([]byte(*cardName)); err != nil {
+			return err
+		}
+
+		opts = append(opts, archive.ForCard(cardType))
+	}
+
+	if *since != "" {
+		date, err := time.Parse(time.DateOnly, *since)
+		if err != nil {
+			return fmt.Errorf("invalid date %q", *since)
+		}
+
+		opts = append(opts, archive.Since(date))
+	}
+
+	arch, err := archive.Open(*fileName)
+	if err != nil {
+		return err
+	}
+
+	defer arch.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "sync":
+		return archiveSync(ctx, arch, stdout, getenv)
+	case "spend":
+		return archiveSpend(ctx, arch, stdout, opts)
+	case "discount":
+		return archiveDiscount(ctx, arch, stdout, opts)
+	}
+
+	return errUsage
+}
+
+func archiveSync(ctx context.Context, arch *archive.Archive, stdout io.Writer, getenv func(string) string) error {
+	client, err := newClient(getenv)
+	if err != nil {
+		return err
+	}
+
+	cards, err := client.DiscoverCardsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	results, err := arch.Sync(ctx, cards, time.Now())
+	for _, result := range results {
+		fmt.Fprintf(stdout, "%s: %d new items\n", result.CardType, result.Added)
+	}
+
+	return err
+}
+
+func archiveSpend(ctx context.Context, arch *archive.Archive, stdout io.Writer, opts []archive.QueryOption) error {
+	spends, err := arch.MonthlySpend(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MONTH\tCARD\tBUSINESS\tPURCHASES\tTOTAL")
+
+	for _, spend := range spends {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.2f\n", spend.Month, spend.CardType, spend.BusinessName, spend.Purchases, spend.Total)
+	}
+
+	return w.Flush()
+}
+
+func archiveDiscount(ctx context.Context, arch *archive.Archive, stdout io.Writer, opts []archive.QueryOption) error {
+	discounts, err := arch.MonthlyDiscounts(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MONTH\tCARD\tLOADED\tPAID\tDISCOUNT")
+
+	total := archive.MonthlyDiscount{}
+
+	for _, discount := range discounts {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f\t%.1f%%\n",
+			discount.Month, discount.CardType, discount.Loaded, discount.Paid, discount.Discount()*100)
+
+		total.Loaded += discount.Loaded
+		total.Paid += discount.Paid
+	}
+
+	fmt.Fprintf(w, "TOTAL\t\t%.2f\t%.2f\t%.1f%%\n", total.Loaded, total.Paid, total.Discount()*100)
+
+	return w.Flush()
+}
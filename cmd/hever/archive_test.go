@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+func TestArchive(t *testing.T) {
+	keva := testutils.NewFakeCard("11111111-2222-3333-4444-555555555555")
+	keva.Balance = 500
+	keva.MonthlyLoaded = 500
+	keva.History = []testutils.FakeHistoryRow{
+		{Id: "1001", Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Load: true, BusinessName: "טעינת כרטיס", Amount: 1000},
+		{Id: "1002", Date: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), BusinessName: "שופרסל", Amount: 300},
+		{Id: "1003", Date: time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC), BusinessName: "שופרסל", Amount: 200},
+	}
+
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username: "TestUsername",
+		Password: "TestPassword",
+		Cards:    map[string]*testutils.FakeCard{testutils.FakeCardKeva: keva},
+	}).SetupTest(t)
+
+	env := map[string]string{
+		"HEVER_USERNAME": "TestUsername",
+		"HEVER_PASSWORD": "TestPassword",
+		"HEVER_BASE_URL": fake.URL(),
+	}
+
+	db := filepath.Join(t.TempDir(), "hever.db")
+
+	hever := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(args, &out, func(key string) string { return env[key] })
+
+		return out.String(), err
+	}
+
+	out, err := hever("archive", "sync", "-db", db)
+	assert.NoError(t, err)
+	assert.Equal(t, "keva: 3 new items\n", out)
+
+	out, err = hever("archive", "sync", "-db", db)
+	assert.NoError(t, err)
+	assert.Equal(t, "keva: 0 new items\n", out)
+
+	out, err = hever("archive", "spend", "-db", db, "-card", "keva")
+	assert.NoError(t, err)
+	assert.Equal(t, `MONTH    CARD  BUSINESS  PURCHASES  TOTAL
+2024-03  keva  שופרסל    2          500.00
+`, out)
+
+	out, err = hever("archive", "discount", "-db", db)
+	assert.NoError(t, err)
+	assert.Equal(t, `MONTH    CARD  LOADED   PAID    DISCOUNT
+2024-03  keva  1000.00  700.00  30.0%
+TOTAL          1000.00  700.00  30.0%
+`, out)
+
+	_, err = hever("archive", "spend", "-db", db, "-card", "nope")
+	assert.ErrorContains(t, err, "unknown card type")
+
+	_, err = hever("archive", "nope", "-db", db)
+	assert.ErrorIs(t, err, errUsage)
+
+	_, err = hever()
+	assert.ErrorIs(t, err, errUsage)
+
+	delete(env, "HEVER_PASSWORD")
+	_, err = hever("archive", "sync", "-db", db)
+	assert.ErrorContains(t, err, "HEVER_PASSWORD")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/analytics"
+)
+
+func runDiscount(args []string, stdout io.Writer, getenv func(string) string) error {
+	flags := flag.NewFlagSet("discount", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	cardName := flags.String("card", "", "only report the given card")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	var only gohever.CardType
+	if *cardName != "" {
+		if err := only.UnmarshalText([]byte(*cardName)); err != nil {
+			return err
+		}
+	}
+
+	client, err := newClient(getenv)
+	if err != nil {
+		return err
+	}
+
+	cards, err := client.DiscoverCards()
+	if err != nil {
+		return err
+	}
+
+	printed := false
+
+	for _, card := range cards {
+		if *cardName != "" && card.Type() != only {
+			continue
+		}
+
+		status, err := card.GetStatus()
+		if err != nil {
+			return fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		history, err := card.GetHistory()
+		if err != nil {
+			return fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		report, err := analytics.NewDiscountReport(*history, status.Factors)
+		if err != nil {
+			return fmt.Errorf("%s: %w", card.Type(), err)
+		}
+
+		if printed {
+			fmt.Fprintln(stdout)
+		}
+
+		printed = true
+
+		fmt.Fprintf(stdout, "%s\n", card.Type())
+		if err := report.WriteText(stdout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+func TestDiscount(t *testing.T) {
+	keva := testutils.NewFakeCard("11111111-2222-3333-4444-555555555555")
+	keva.History = []testutils.FakeHistoryRow{
+		{Id: "1001", Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Load: true, BusinessName: "טעינת כרטיס", Amount: 800},
+		{Id: "1002", Date: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), BusinessName: "שופרסל", Amount: 300},
+		{Id: "1003", Date: time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC), Load: true, BusinessName: "טעינת כרטיס", Amount: 400},
+	}
+
+	teamim := testutils.NewFakeCard("66666666-7777-8888-9999-000000000000")
+
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username: "TestUsername",
+		Password: "TestPassword",
+		Cards: map[string]*testutils.FakeCard{
+			testutils.FakeCardKeva:   keva,
+			testutils.FakeCardTeamim: teamim,
+		},
+	}).SetupTest(t)
+
+	env := map[string]string{
+		"HEVER_USERNAME": "TestUsername",
+		"HEVER_PASSWORD": "TestPassword",
+		"HEVER_BASE_URL": fake.URL(),
+	}
+
+	hever := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(args, &out, func(key string) string { return env[key] })
+
+		return out.String(), err
+	}
+
+	out, err := hever("discount", "-card", "keva")
+	assert.NoError(t, err)
+	assert.Equal(t, `keva
+FACTORS: 1000.00 at 0.70, 1000.00 at 0.75, 1000.00 at 0.80
+DATE        LOADED   PAID    DISCOUNT  LEFT ON TABLE  TIERS
+01/03/2024  800.00   560.00  30.0%     -              800.00 at 0.70
+09/03/2024  400.00   290.00  27.5%     -              200.00 at 0.70, 200.00 at 0.75
+2024-03     1200.00  850.00  29.2%     200.00         unused 800.00 at 0.75, 1000.00 at 0.80
+TOTAL       1200.00  850.00  29.2%     200.00
+`, out)
+
+	out, err = hever("discount")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "keva\nFACTORS")
+	assert.Contains(t, out, "\n\nteamim\nFACTORS")
+
+	_, err = hever("discount", "-card", "nope")
+	assert.ErrorContains(t, err, "unknown card type")
+
+	_, err = hever("discount", "-nope")
+	assert.ErrorIs(t, err, errUsage)
+}
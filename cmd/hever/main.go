@@ -0,0 +1,84 @@
+// Command hever works with HEVER cards from the command line.
+//
+// The account is read from the environment: HEVER_USERNAME and HEVER_PASSWORD, and optionally
+// HEVER_FLAVOR (e.g. "mcc") and HEVER_BASE_URL.
+//
+// Usage:
+//
+//	hever archive sync [-db hever.db]
+//	hever archive spend [-db hever.db] [-card keva] [-since 2024-01-01]
+//	hever archive discount [-db hever.db] [-card keva]
+//	hever discount [-card keva]
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yardnsm/gohever"
+)
+
+const usage = `usage: hever <command> [arguments]
+
+commands:
+  archive sync       archive the history and status of the cards
+  archive spend      show the monthly spend per business
+  archive discount   show the discount achieved on the loads
+  discount           show the tiers and discount of every load in the history
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Getenv)
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hever: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer, getenv func(string) string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "archive":
+		return runArchive(args[1:], stdout, getenv)
+	case "discount":
+		return runDiscount(args[1:], stdout, getenv)
+	}
+
+	return errUsage
+}
+
+// Creates a client for the account in the environment
+func newClient(getenv func(string) string) (*gohever.Client, error) {
+	username, password := getenv("HEVER_USERNAME"), getenv("HEVER_PASSWORD")
+	if username == "" || password == "" {
+		return nil, errors.New("HEVER_USERNAME and HEVER_PASSWORD should be set")
+	}
+
+	flavorName := getenv("HEVER_FLAVOR")
+	if flavorName == "" {
+		flavorName = gohever.FlavorHvr().Name
+	}
+
+	flavor, ok := gohever.LookupFlavor(flavorName)
+	if !ok {
+		return nil, fmt.Errorf("unknown flavor %q", flavorName)
+	}
+
+	return gohever.NewClient(flavor, gohever.Config{
+		Credentials: gohever.BasicCredentials(username, password),
+		BaseURL:     getenv("HEVER_BASE_URL"),
+	}), nil
+}
@@ -1,6 +1,13 @@
 package gohever
 
-import "github.com/go-resty/resty/v2"
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type Credentials struct {
 	Username string
@@ -8,15 +15,46 @@ type Credentials struct {
 }
 
 type CreditCard struct {
+	// A name used to select the credit card when loading, e.g. "personal" or "work"
+	Name string
+
 	Number string
 	Month  string
 	Year   string
 }
 
 type Config struct {
-	InitResty      func(r *resty.Client)
+	// Customize the underlying resty client. Note that the client already registers request and
+	// response log callbacks that redact secrets from the debug logs; overriding them will disable
+	// the redaction.
+	InitResty   func(r *resty.Client)
 	Credentials func() (Credentials, error)
 	CreditCard  func() (CreditCard, error)
+
+	// A named set of credit cards. When set, it takes precedence over CreditCard, and the first
+	// credit card in the set is the default one.
+	CreditCards func() ([]CreditCard, error)
+
+	// Overrides the base URL of the flavor, e.g. for a staging mirror or a local test server. All
+	// of the site's paths are resolved against it.
+	BaseURL string
+
+	// An optional HTTP proxy to send all requests through, e.g. "http://proxy.corp:8080"
+	Proxy string
+
+	// An optional logger for reporting authentication, session expiry, endpoint calls and parse
+	// failures. Nothing is logged when it's nil.
+	Logger *slog.Logger
+
+	// Optional OpenTelemetry providers for tracing the public operations and the underlying
+	// endpoint calls, and for reporting logins, loads and latencies. The global providers are used
+	// when these are nil.
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+
+	// Allows blocking, unblocking and replacing cards. Off by default, as the management flow isn't
+	// verified against a recording of the site yet and a replacement can't be undone.
+	EnableManagement bool
 }
 
 func BasicCredentials(username, password string) func() (Credentials, error) {
@@ -32,8 +70,83 @@ func BasicCreditCard(number, month, year string) func() (CreditCard, error) {
 	return func() (CreditCard, error) {
 		return CreditCard{
 			Number: number,
-			Month: month,
-			Year: year,
+			Month:  month,
+			Year:   year,
 		}, nil
 	}
 }
+
+func BasicCreditCards(creditCards ...CreditCard) func() ([]CreditCard, error) {
+	return func() ([]CreditCard, error) {
+		return creditCards, nil
+	}
+}
+
+// Credentials and credit cards should never be printed as is, so they'll end up redacted in logs
+// and errors
+
+func (credentials Credentials) String() string {
+	return fmt.Sprintf("Credentials{Username: %s, Password: %s}", redactedValue, redactedValue)
+}
+
+func (credentials Credentials) GoString() string {
+	return "gohever." + credentials.String()
+}
+
+func (creditCard CreditCard) String() string {
+	return fmt.Sprintf("CreditCard{Name: %s, Number: %s, Month: %s, Year: %s}",
+		creditCard.Name, MaskCardNumber(creditCard.Number), redactedValue, redactedValue)
+}
+
+func (creditCard CreditCard) GoString() string {
+	return "gohever." + creditCard.String()
+}
+
+// Returns all of the configured credit cards, the default one first
+func (config *Config) creditCards() ([]CreditCard, error) {
+	if config.CreditCards != nil {
+		creditCards, err := config.CreditCards()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(creditCards) == 0 {
+			return nil, ErrNoCreditCards
+		}
+
+		return creditCards, nil
+	}
+
+	if config.CreditCard != nil {
+		creditCard, err := config.CreditCard()
+		if err != nil {
+			return nil, err
+		}
+
+		return []CreditCard{creditCard}, nil
+	}
+
+	return nil, ErrNoCreditCards
+}
+
+// Orders the credit cards so the one with the given name comes first, followed by the rest of the
+// cards in their original order. An empty name keeps the default order.
+func selectCreditCard(creditCards []CreditCard, name string) ([]CreditCard, error) {
+	if name == "" {
+		return creditCards, nil
+	}
+
+	for i, creditCard := range creditCards {
+		if creditCard.Name != name {
+			continue
+		}
+
+		selected := []CreditCard{creditCard}
+		selected = append(selected, creditCards[:i]...)
+		selected = append(selected, creditCards[i+1:]...)
+
+		return selected, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrCreditCardNotFound, name)
+}
@@ -14,10 +14,23 @@ const (
 	urlAuthenticate   = "signin.aspx?bs=1"
 	urlDeauthenticate = "site/logout"
 
-	urlCardConfig  = "orders/gift_2000.aspx"
-	urlCardStatus  = "orders/gift_2000.aspx"
-	urlCardHistory = "orders/gift_2000.aspx"
-	urlLoadCard    = "orders/gift_2000.aspx"
+	urlGiftCard = "orders/gift_2000.aspx"
+)
+
+// Endpoint names, used when reporting requests
+const (
+	endpointAuthConfig     = "auth_config"
+	endpointAuthPixel      = "auth_pixel"
+	endpointAuthenticate   = "authenticate"
+	endpointDeauthenticate = "deauthenticate"
+
+	endpointCardConfig  = "card_config"
+	endpointCardBalance = "card_balance"
+	endpointCardHistory = "card_history"
+	endpointLoadCard    = "load_card"
+
+	endpointManageRequest = "manage_request"
+	endpointManageConfirm = "manage_confirm"
 )
 
 // Query Params
@@ -28,6 +41,9 @@ const (
 // Parameters
 const (
 	minimumLoadAmount = 5
+
+	// The prefix of the IDs given to history items without one
+	noIdPrefix = "no_id_"
 )
 
 // Errors
@@ -37,10 +53,32 @@ var (
 	ErrNotAuthenticated    = errors.New("not authenticated to HEVER website")
 	ErrAuthenticatedFailed = errors.New("failed to authenticate to HEVER website")
 
-	ErrUnableToParseCardConfig = errors.New("failed to parse the card config")
+	ErrCardNotSupported = errors.New("card is not supported by the site flavor")
+
+	ErrInvalidBaseURL = errors.New("invalid base URL")
+
+	ErrInvalidFlavor           = errors.New("a flavor must have a name and a base URL")
+	ErrFlavorAlreadyRegistered = errors.New("a flavor with the same name is already registered")
+
+	ErrUnableToParseCardConfig  = errors.New("failed to parse the card config")
+	ErrUnableToParseCardBalance = errors.New("failed to parse the card balance")
+
+	ErrUnableToParseConfirmation = errors.New("failed to parse the action confirmation")
+
+	ErrManagementNotEnabled = errors.New("managing cards is not enabled")
 
 	ErrNotEnoughToLoad       = errors.New("the amount to load should be above 5")
 	ErrLoadAboveOnCardLimit  = errors.New("charging above the max on card limit")
 	ErrLoadAboveMonthlyLimit = errors.New("charging above the max monthly limit")
 	ErrLoadInvalidValue      = errors.New("invalid value was passed to load")
+
+	ErrNoCreditCards      = errors.New("no credit cards were configured")
+	ErrCreditCardNotFound = errors.New("credit card was not found in config")
+
+	ErrUnknownCardType   = errors.New("unknown card type")
+	ErrUnknownCardAction = errors.New("unknown card action")
+	ErrUnknownLoadStatus = errors.New("unknown load status")
+
+	ErrUnknownManageAction  = errors.New("unknown manage action")
+	ErrUnknownManageOutcome = errors.New("unknown manage outcome")
 )
@@ -0,0 +1,86 @@
+package gohever
+
+import "fmt"
+
+// The enums are encoded by their names rather than their values, so stored snapshots keep working
+// when the values are reordered
+
+var (
+	cardTypes    = []CardType{TypeKeva, TypeTeamim, TypeSheli}
+	cardActions  = []CardAction{ActionLoad, ActionPurchase}
+	loadStatuses = []LoadStatus{StatusNone, StatusError, StatusSuccess}
+
+	manageActions  = []ManageAction{ManageBlock, ManageUnblock, ManageReplace}
+	manageOutcomes = []ManageOutcome{OutcomeNone, OutcomeRejected, OutcomeDone}
+)
+
+type enum interface {
+	~int
+	fmt.Stringer
+}
+
+func marshalEnum[T enum](value T, values []T, err error) ([]byte, error) {
+	for _, v := range values {
+		if v == value {
+			return []byte(value.String()), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d", err, int(value))
+}
+
+func unmarshalEnum[T enum](text []byte, values []T, err error) (T, error) {
+	for _, v := range values {
+		if v.String() == string(text) {
+			return v, nil
+		}
+	}
+
+	var zero T
+	return zero, fmt.Errorf("%w: %q", err, text)
+}
+
+func (cardType CardType) MarshalText() ([]byte, error) {
+	return marshalEnum(cardType, cardTypes, ErrUnknownCardType)
+}
+
+func (cardType *CardType) UnmarshalText(text []byte) (err error) {
+	*cardType, err = unmarshalEnum(text, cardTypes, ErrUnknownCardType)
+	return err
+}
+
+func (action CardAction) MarshalText() ([]byte, error) {
+	return marshalEnum(action, cardActions, ErrUnknownCardAction)
+}
+
+func (action *CardAction) UnmarshalText(text []byte) (err error) {
+	*action, err = unmarshalEnum(text, cardActions, ErrUnknownCardAction)
+	return err
+}
+
+func (status LoadStatus) MarshalText() ([]byte, error) {
+	return marshalEnum(status, loadStatuses, ErrUnknownLoadStatus)
+}
+
+func (status *LoadStatus) UnmarshalText(text []byte) (err error) {
+	*status, err = unmarshalEnum(text, loadStatuses, ErrUnknownLoadStatus)
+	return err
+}
+
+func (action ManageAction) MarshalText() ([]byte, error) {
+	return marshalEnum(action, manageActions, ErrUnknownManageAction)
+}
+
+func (action *ManageAction) UnmarshalText(text []byte) (err error) {
+	*action, err = unmarshalEnum(text, manageActions, ErrUnknownManageAction)
+	return err
+}
+
+func (outcome ManageOutcome) MarshalText() ([]byte, error) {
+	return marshalEnum(outcome, manageOutcomes, ErrUnknownManageOutcome)
+}
+
+func (outcome *ManageOutcome) UnmarshalText(text []byte) (err error) {
+	*outcome, err = unmarshalEnum(text, manageOutcomes, ErrUnknownManageOutcome)
+	return err
+}
@@ -0,0 +1,184 @@
+package gohever
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumEncoding(t *testing.T) {
+	t.Run("should encode the enums by their names", func(t *testing.T) {
+		data, err := json.Marshal(map[string]any{
+			"type":   TypeTeamim,
+			"action": ActionPurchase,
+			"status": StatusSuccess,
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type": "teamim", "action": "purchase", "status": "success"}`, string(data))
+	})
+
+	t.Run("should round trip every value", func(t *testing.T) {
+		for _, cardType := range cardTypes {
+			var decoded CardType
+			data, _ := cardType.MarshalText()
+
+			assert.NoError(t, decoded.UnmarshalText(data))
+			assert.Equal(t, cardType, decoded)
+		}
+
+		for _, action := range cardActions {
+			var decoded CardAction
+			data, _ := action.MarshalText()
+
+			assert.NoError(t, decoded.UnmarshalText(data))
+			assert.Equal(t, action, decoded)
+		}
+
+		for _, status := range loadStatuses {
+			var decoded LoadStatus
+			data, _ := status.MarshalText()
+
+			assert.NoError(t, decoded.UnmarshalText(data))
+			assert.Equal(t, status, decoded)
+		}
+
+		for _, action := range manageActions {
+			var decoded ManageAction
+			data, _ := action.MarshalText()
+
+			assert.NoError(t, decoded.UnmarshalText(data))
+			assert.Equal(t, action, decoded)
+		}
+
+		for _, outcome := range manageOutcomes {
+			var decoded ManageOutcome
+			data, _ := outcome.MarshalText()
+
+			assert.NoError(t, decoded.UnmarshalText(data))
+			assert.Equal(t, outcome, decoded)
+		}
+	})
+
+	t.Run("should fail on unknown values", func(t *testing.T) {
+		_, err := json.Marshal(CardType(42))
+		assert.ErrorIs(t, err, ErrUnknownCardType)
+
+		var cardType CardType
+		assert.ErrorIs(t, json.Unmarshal([]byte(`"nope"`), &cardType), ErrUnknownCardType)
+
+		var action CardAction
+		assert.ErrorIs(t, json.Unmarshal([]byte(`"loaded"`), &action), ErrUnknownCardAction)
+
+		var status LoadStatus
+		assert.ErrorIs(t, status.UnmarshalText([]byte("unknown")), ErrUnknownLoadStatus)
+	})
+}
+
+func TestTypesEncoding(t *testing.T) {
+	t.Run("CardStatus", func(t *testing.T) {
+		status := CardStatus{
+			Factors:                []CardFactor{{Factor: 0.7, Amount: 1000}, {Factor: 0.75, Amount: 1000}},
+			MaxMonthlyAmount:       3000,
+			MaxOnCardAmount:        1000,
+			CurrentBalance:         150.5,
+			RemainingMonthlyAmount: 2800,
+			RemainingOnCardAmount:  849.5,
+			MonthlyUsage:           200,
+			Leftovers:              12.3,
+			SerialNumber:           "11111111-2222-3333-4444-555555555555",
+		}
+
+		data, err := json.Marshal(status)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"factors": [{"factor": 0.7, "amount": 1000}, {"factor": 0.75, "amount": 1000}],
+			"max_monthly_amount": 3000,
+			"max_on_card_amount": 1000,
+			"current_balance": 150.5,
+			"remaining_monthly_amount": 2800,
+			"remaining_on_card_amount": 849.5,
+			"monthly_usage": 200,
+			"leftovers": 12.3,
+			"serial_number": "11111111-2222-3333-4444-555555555555"
+		}`, string(data))
+
+		var decoded CardStatus
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, status, decoded)
+	})
+
+	t.Run("CardEstimate", func(t *testing.T) {
+		estimate := CardEstimate{
+			Total:            500,
+			TotalFactored:    350,
+			Required:         500,
+			RequiredFactored: 350,
+			Leftovers:        0,
+			Factors:          []CardFactor{{Factor: 0.7, Amount: 500}},
+		}
+
+		data, err := json.Marshal(estimate)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"total": 500,
+			"total_factored": 350,
+			"required": 500,
+			"required_factored": 350,
+			"leftovers": 0,
+			"factors": [{"factor": 0.7, "amount": 500}]
+		}`, string(data))
+
+		var decoded CardEstimate
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, estimate, decoded)
+	})
+
+	t.Run("CardHistoryItem", func(t *testing.T) {
+		item := CardHistoryItem{
+			Id:           "1234",
+			Date:         "14/03/2024",
+			ActionType:   ActionPurchase,
+			BusinessName: "שופרסל",
+			Amount:       52.5,
+		}
+
+		data, err := json.Marshal(item)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"id": "1234",
+			"date": "14/03/2024",
+			"action": "purchase",
+			"business_name": "שופרסל",
+			"amount": 52.5
+		}`, string(data))
+
+		var decoded CardHistoryItem
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, item, decoded)
+	})
+
+	t.Run("LoadResult", func(t *testing.T) {
+		result := LoadResult{
+			Status:     StatusSuccess,
+			LoadNumber: "10000001",
+			RawMessage: "הטעינה בוצעה בהצלחה",
+			CreditCard: "personal",
+		}
+
+		data, err := json.Marshal(result)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"status": "success",
+			"load_number": "10000001",
+			"raw_message": "הטעינה בוצעה בהצלחה",
+			"declined": false,
+			"credit_card": "personal"
+		}`, string(data))
+
+		var decoded LoadResult
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, result, decoded)
+	})
+}
@@ -14,8 +14,12 @@ func main() {
 		CreditCard:  gohever.BasicCreditCard("123456789", "04", "27"),
 	}
 
-	hvr := gohever.NewClient(gohever.FlavorHvr, config)
-	keva := hvr.Cards.Keva
+	hvr := gohever.NewClient(gohever.FlavorHvr(), config)
+
+	keva, err := hvr.Card(gohever.TypeKeva)
+	if err != nil {
+		log.Fatalf("unable to get the keva card: %v\n", err)
+	}
 
 	status, err := keva.GetStatus()
 	if err != nil {
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/yardnsm/gohever"
+)
+
+var csvHeader = []string{"id", "date", "account", "type", "description", "amount", "currency"}
+
+// Writes the history as CSV, one row per item. Amounts are signed, negative for debits.
+func WriteCSV(w io.Writer, cardType gohever.CardType, items []gohever.CardHistoryItem, opts ...Option) error {
+	options := newOptions(opts)
+
+	transactions, err := newTransactions(cardType, items, options)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeader)
+
+	for _, t := range transactions {
+		kind := "credit"
+		if t.debit {
+			kind = "debit"
+		}
+
+		writer.Write([]string{
+			t.id,
+			t.date.Format("2006-01-02"),
+			options.account(cardType),
+			kind,
+			t.description,
+			strconv.FormatFloat(t.amount, 'f', 2, 64),
+			options.currency,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
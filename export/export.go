@@ -0,0 +1,200 @@
+// Package export writes the history of cards in formats accounting tools can import: CSV, OFX (and
+// QFX) bank statements and ledger/hledger journals.
+//
+// Every item is exported from the card's point of view: purchases are debits, taking money off the
+// card, and loads are credits.
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+const (
+	defaultCurrency        = "ILS"
+	defaultExpensesAccount = "Expenses:Hever"
+	defaultFundingAccount  = "Liabilities:CreditCard"
+	defaultDiscountAccount = "Income:Hever:Discount"
+)
+
+// An option for the exporters
+type Option func(options *options)
+
+type options struct {
+	accounts        map[gohever.CardType]string
+	expensesAccount string
+	fundingAccount  string
+	discountAccount string
+	factors         []gohever.CardFactor
+	currency        string
+	intuBID         string
+	location        *time.Location
+}
+
+func newOptions(opts []Option) options {
+	options := options{
+		accounts:        map[gohever.CardType]string{},
+		expensesAccount: defaultExpensesAccount,
+		fundingAccount:  defaultFundingAccount,
+		discountAccount: defaultDiscountAccount,
+		currency:        defaultCurrency,
+		location:        time.UTC,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// The account of the card, e.g. "Assets:Hever:Keva"
+func (options options) account(cardType gohever.CardType) string {
+	if account, ok := options.accounts[cardType]; ok {
+		return account
+	}
+
+	name := cardType.String()
+	return "Assets:Hever:" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// Sets the account name of a card. Defaults to "Assets:Hever:<Type>", e.g. "Assets:Hever:Keva".
+func WithAccount(cardType gohever.CardType, account string) Option {
+	return func(options *options) {
+		options.accounts[cardType] = account
+	}
+}
+
+// Sets the account purchases are booked against in ledger journals. Defaults to "Expenses:Hever".
+func WithExpensesAccount(account string) Option {
+	return func(options *options) {
+		options.expensesAccount = account
+	}
+}
+
+// Sets the account loads are paid from in ledger journals. Defaults to "Liabilities:CreditCard".
+func WithFundingAccount(account string) Option {
+	return func(options *options) {
+		options.fundingAccount = account
+	}
+}
+
+// Sets the account the discount on loads is booked to in ledger journals, see WithFactors.
+// Defaults to "Income:Hever:Discount".
+func WithDiscountAccount(account string) Option {
+	return func(options *options) {
+		options.discountAccount = account
+	}
+}
+
+// Sets the factors of the card, from its status. The history only shows the face value of loads,
+// so ledger journals book loads at the price paid for them only when the factors are given.
+func WithFactors(factors []gohever.CardFactor) Option {
+	return func(options *options) {
+		options.factors = factors
+	}
+}
+
+// Sets the currency of the amounts. Defaults to "ILS".
+func WithCurrency(currency string) Option {
+	return func(options *options) {
+		options.currency = currency
+	}
+}
+
+// Adds the Intuit bank ID to OFX statements, so Quicken imports them as QFX
+func WithIntuBID(bid string) Option {
+	return func(options *options) {
+		options.intuBID = bid
+	}
+}
+
+// Sets the time zone of the history dates. Defaults to UTC.
+func WithLocation(location *time.Location) Option {
+	return func(options *options) {
+		options.location = location
+	}
+}
+
+// A stable ID of a history item, the same across exports, e.g. "keva-12345". Items without an ID
+// of the site get one made of their date, action, business and amount, as the ID given to them by
+// their position shifts once the history grows.
+func TransactionID(cardType gohever.CardType, item gohever.CardHistoryItem) string {
+	if item.HasId() {
+		return cardType.String() + "-" + item.Id
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		item.Date,
+		item.ActionType.String(),
+		strings.TrimSpace(item.BusinessName),
+		strconv.FormatFloat(math.Abs(item.Amount), 'f', 2, 64),
+	}, "\x00")))
+
+	return cardType.String() + "-" + hex.EncodeToString(sum[:8])
+}
+
+// A history item from the card's point of view
+type transaction struct {
+	id          string
+	date        time.Time
+	debit       bool
+	description string
+
+	// Negative for debits
+	amount float64
+}
+
+func newTransaction(cardType gohever.CardType, item gohever.CardHistoryItem, options options) (transaction, error) {
+	date, err := item.ParseDate(options.location)
+	if err != nil {
+		return transaction{}, fmt.Errorf("item %s: %w", item.Id, err)
+	}
+
+	t := transaction{
+		id:          TransactionID(cardType, item),
+		date:        date,
+		description: strings.TrimSpace(item.BusinessName),
+		amount:      math.Abs(item.Amount),
+	}
+
+	if item.ActionType == gohever.ActionPurchase {
+		t.debit = true
+		t.amount = -t.amount
+	}
+
+	if t.description == "" {
+		t.description = "Hever " + item.ActionType.String()
+	}
+
+	return t, nil
+}
+
+func newTransactions(cardType gohever.CardType, items []gohever.CardHistoryItem, options options) ([]transaction, error) {
+	transactions := make([]transaction, 0, len(items))
+	seen := make(map[string]int)
+
+	for _, item := range items {
+		t, err := newTransaction(cardType, item, options)
+		if err != nil {
+			return nil, err
+		}
+
+		// Identical items without an ID, e.g. two coffees on the same day, are told apart by order
+		seen[t.id]++
+		if n := seen[t.id]; n > 1 {
+			t.id += "-" + strconv.Itoa(n)
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+)
+
+var testHistory = []gohever.CardHistoryItem{
+	{Id: "1001", Date: "01/03/2024", ActionType: gohever.ActionLoad, BusinessName: "טעינת כרטיס", Amount: 400},
+	{Id: "1002", Date: "14/03/2024 12:30", ActionType: gohever.ActionPurchase, BusinessName: " שופרסל דיל ", Amount: 52.5},
+	{Id: "1003", Date: "15/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "Ben & Jerry's <Tel Aviv> | Dizengoff Center", Amount: 20},
+}
+
+func TestTransactionID(t *testing.T) {
+	assert.Equal(t, "keva-1002", TransactionID(gohever.TypeKeva, testHistory[1]))
+	assert.Equal(t, "teamim-1002", TransactionID(gohever.TypeTeamim, testHistory[1]))
+
+	t.Run("should not depend on the position of items without an ID", func(t *testing.T) {
+		item := gohever.CardHistoryItem{Id: "no_id_0", Date: "14/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "קפה", Amount: 12}
+
+		id := TransactionID(gohever.TypeKeva, item)
+		assert.Regexp(t, "^keva-[0-9a-f]{16}$", id)
+
+		item.Id = "no_id_7"
+		assert.Equal(t, id, TransactionID(gohever.TypeKeva, item))
+
+		item.Amount = 13
+		assert.NotEqual(t, id, TransactionID(gohever.TypeKeva, item))
+	})
+
+	t.Run("should tell identical items without an ID apart", func(t *testing.T) {
+		coffee := gohever.CardHistoryItem{Id: "no_id_0", Date: "14/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "קפה", Amount: 12}
+		second := coffee
+		second.Id = "no_id_1"
+
+		var out bytes.Buffer
+		assert.NoError(t, WriteCSV(&out, gohever.TypeKeva, []gohever.CardHistoryItem{coffee, second}))
+
+		id := TransactionID(gohever.TypeKeva, coffee)
+		assert.Contains(t, out.String(), "\n"+id+",")
+		assert.Contains(t, out.String(), "\n"+id+"-2,")
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+
+	err := WriteCSV(&out, gohever.TypeKeva, testHistory, WithAccount(gohever.TypeKeva, "Assets:Benefits:Keva"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, `id,date,account,type,description,amount,currency
+keva-1001,2024-03-01,Assets:Benefits:Keva,credit,טעינת כרטיס,400.00,ILS
+keva-1002,2024-03-14,Assets:Benefits:Keva,debit,שופרסל דיל,-52.50,ILS
+keva-1003,2024-03-15,Assets:Benefits:Keva,debit,Ben & Jerry's <Tel Aviv> | Dizengoff Center,-20.00,ILS
+`, out.String())
+}
+
+func TestWriteOFX(t *testing.T) {
+	var out bytes.Buffer
+
+	err := WriteOFX(&out, gohever.TypeTeamim, testHistory, WithIntuBID("12345"))
+	assert.NoError(t, err)
+
+	ofx := strings.ReplaceAll(out.String(), "\r\n", "\n")
+
+	assert.True(t, strings.HasPrefix(ofx, "OFXHEADER:100\nDATA:OFXSGML\nVERSION:102\nSECURITY:NONE\nENCODING:UNICODE\nCHARSET:NONE\n"))
+	assert.Contains(t, ofx, "<INTU.BID>12345\n")
+	assert.Contains(t, ofx, "<CURDEF>ILS\n<BANKACCTFROM>\n<BANKID>HEVER\n<ACCTID>Assets:Hever:Teamim\n")
+	assert.Contains(t, ofx, "<DTSTART>20240301000000\n<DTEND>20240315000000\n")
+
+	assert.Contains(t, ofx, `<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240301000000
+<TRNAMT>400.00
+<FITID>teamim-1001
+<NAME>טעינת כרטיס
+</STMTTRN>`)
+
+	assert.Contains(t, ofx, `<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240314123000
+<TRNAMT>-52.50
+<FITID>teamim-1002
+<NAME>שופרסל דיל
+</STMTTRN>`)
+
+	// Long names are truncated, and kept in full in the memo
+	assert.Contains(t, ofx, `<NAME>Ben &amp; Jerry's &lt;Tel Aviv&gt; | Dizen
+<MEMO>Ben &amp; Jerry's &lt;Tel Aviv&gt; | Dizengoff Center
+`)
+
+	assert.True(t, strings.HasSuffix(ofx, "</BANKTRANLIST>\n</STMTRS>\n</STMTTRNRS>\n</BANKMSGSRSV1>\n</OFX>\n"))
+}
+
+func TestWriteLedger(t *testing.T) {
+	var out bytes.Buffer
+
+	err := WriteLedger(&out, gohever.TypeKeva, testHistory,
+		WithExpensesAccount("Expenses:Food"),
+		WithFundingAccount("Liabilities:Visa"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, `2024-03-01 * (keva-1001) טעינת כרטיס
+    Assets:Hever:Keva  400.00 ILS
+    Liabilities:Visa  -400.00 ILS
+
+2024-03-14 * (keva-1002) שופרסל דיל
+    Assets:Hever:Keva  -52.50 ILS
+    Expenses:Food  52.50 ILS
+
+2024-03-15 * (keva-1003) Ben & Jerry's <Tel Aviv> / Dizengoff Center
+    Assets:Hever:Keva  -20.00 ILS
+    Expenses:Food  20.00 ILS
+`, out.String())
+}
+
+func TestWriteLedgerPrices(t *testing.T) {
+	factors := []gohever.CardFactor{
+		{Factor: 0.7, Amount: 100},
+		{Factor: 0.8, Amount: 200},
+		{Factor: 0.9, Amount: 1000},
+	}
+
+	t.Run("should book loads at the price paid and the discount as income", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := WriteLedger(&out, gohever.TypeKeva, testHistory[:2],
+			WithFactors(factors),
+			WithDiscountAccount("Income:Discounts"))
+
+		assert.NoError(t, err)
+
+		assert.Equal(t, `2024-03-01 * (keva-1001) טעינת כרטיס
+    Assets:Hever:Keva  400.00 ILS
+    Liabilities:CreditCard  -320.00 ILS
+    Income:Discounts  -80.00 ILS
+
+2024-03-14 * (keva-1002) שופרסל דיל
+    Assets:Hever:Keva  -52.50 ILS
+    Expenses:Hever  52.50 ILS
+`, out.String())
+	})
+
+	t.Run("should price identical loads without an ID by their order", func(t *testing.T) {
+		load := gohever.CardHistoryItem{Id: "no_id_0", Date: "02/03/2024", ActionType: gohever.ActionLoad, Amount: 66.67}
+		second := load
+		second.Id = "no_id_1"
+
+		var out bytes.Buffer
+
+		err := WriteLedger(&out, gohever.TypeKeva, []gohever.CardHistoryItem{load, second}, WithFactors(factors))
+
+		assert.NoError(t, err)
+
+		// The first load is charged by the cheapest tier, and the second by both of the first tiers
+		assert.Contains(t, out.String(), "    Liabilities:CreditCard  -46.67 ILS\n    Income:Hever:Discount  -20.00 ILS\n")
+		assert.Contains(t, out.String(), "    Liabilities:CreditCard  -50.00 ILS\n    Income:Hever:Discount  -16.67 ILS\n")
+	})
+}
+
+func TestInvalidDate(t *testing.T) {
+	err := WriteCSV(&bytes.Buffer{}, gohever.TypeKeva, []gohever.CardHistoryItem{{Id: "1", Date: "yesterday"}})
+	assert.EqualError(t, err, `item 1: unable to parse date "yesterday"`)
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/analytics"
+)
+
+// Writes the history as a plain-text journal, readable by both ledger and hledger. Purchases are
+// booked from the card's account to the expenses account, and loads from the funding account to
+// the card's account. The transaction ID is kept as the transaction's code.
+//
+// The history only shows the face value of loads, so they're booked at it unless the card's factors
+// are given using WithFactors. Then the funding account is charged the price paid, worked out the
+// same way as analytics.NewDiscountReport does, and the discount is booked to the discount account.
+func WriteLedger(w io.Writer, cardType gohever.CardType, items []gohever.CardHistoryItem, opts ...Option) error {
+	options := newOptions(opts)
+
+	transactions, err := newTransactions(cardType, items, options)
+	if err != nil {
+		return err
+	}
+
+	prices, err := loadPrices(cardType, items, options.factors)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+
+	for i, t := range transactions {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+
+		fmt.Fprintf(out, "%s * (%s) %s\n", t.date.Format("2006-01-02"), t.id, ledgerPayee(t.description))
+		fmt.Fprintf(out, "    %s  %.2f %s\n", options.account(cardType), t.amount, options.currency)
+
+		if price, ok := prices[i]; ok {
+			fmt.Fprintf(out, "    %s  %.2f %s\n", options.fundingAccount, -price, options.currency)
+			fmt.Fprintf(out, "    %s  %.2f %s\n", options.discountAccount, price-t.amount, options.currency)
+			continue
+		}
+
+		other := options.fundingAccount
+		if t.debit {
+			other = options.expensesAccount
+		}
+
+		fmt.Fprintf(out, "    %s  %.2f %s\n", other, -t.amount, options.currency)
+	}
+
+	return out.Flush()
+}
+
+// Works out the price paid for every load of the history, rounded to two decimals so the entries
+// balance, keyed by the position of the load in the history
+func loadPrices(cardType gohever.CardType, items []gohever.CardHistoryItem, factors []gohever.CardFactor) (map[int]float64, error) {
+	if len(factors) == 0 {
+		return nil, nil
+	}
+
+	report, err := analytics.NewDiscountReport(items, factors)
+	if err != nil {
+		return nil, err
+	}
+
+	// The report orders the loads by date, so they're matched back by their IDs. Identical loads
+	// without an ID keep their order, as the report's sort is stable.
+	paid := make(map[string][]float64)
+	for _, month := range report.Months {
+		for _, load := range month.Loads {
+			id := TransactionID(cardType, load.Item)
+			paid[id] = append(paid[id], load.Paid)
+		}
+	}
+
+	prices := make(map[int]float64)
+	for i, item := range items {
+		id := TransactionID(cardType, item)
+		if item.ActionType != gohever.ActionLoad || len(paid[id]) == 0 {
+			continue
+		}
+
+		prices[i] = math.Round(paid[id][0]*100) / 100
+		paid[id] = paid[id][1:]
+	}
+
+	return prices, nil
+}
+
+// Payees are a single line, and a pipe splits them into a payee and a note in hledger
+func ledgerPayee(description string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ", "|", "/").Replace(description)
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+const (
+	ofxHeader = "OFXHEADER:100\r\nDATA:OFXSGML\r\nVERSION:102\r\nSECURITY:NONE\r\nENCODING:UNICODE\r\n" +
+		"CHARSET:NONE\r\nCOMPRESSION:NONE\r\nOLDFILEUID:NONE\r\nNEWFILEUID:NONE\r\n\r\n"
+
+	ofxBankID = "HEVER"
+
+	// OFX 1.x limits the payee name to 32 characters, the full name goes in the memo
+	ofxMaxNameLength = 32
+)
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Writes the history as an OFX 1.02 bank statement of the card's account. Use WithIntuBID to make
+// a QFX statement for Quicken.
+func WriteOFX(w io.Writer, cardType gohever.CardType, items []gohever.CardHistoryItem, opts ...Option) error {
+	options := newOptions(opts)
+
+	transactions, err := newTransactions(cardType, items, options)
+	if err != nil {
+		return err
+	}
+
+	var start, end time.Time
+	for _, t := range transactions {
+		if start.IsZero() || t.date.Before(start) {
+			start = t.date
+		}
+
+		if end.IsZero() || t.date.After(end) {
+			end = t.date
+		}
+	}
+
+	if end.IsZero() {
+		end = time.Now().In(options.location)
+		start = end
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteString(ofxHeader)
+
+	out.WriteString("<OFX>\r\n<SIGNONMSGSRSV1>\r\n<SONRS>\r\n")
+	out.WriteString("<STATUS>\r\n<CODE>0\r\n<SEVERITY>INFO\r\n</STATUS>\r\n")
+	fmt.Fprintf(out, "<DTSERVER>%s\r\n<LANGUAGE>ENG\r\n", ofxDate(end))
+
+	if options.intuBID != "" {
+		fmt.Fprintf(out, "<INTU.BID>%s\r\n", ofxEscaper.Replace(options.intuBID))
+	}
+
+	out.WriteString("</SONRS>\r\n</SIGNONMSGSRSV1>\r\n")
+
+	out.WriteString("<BANKMSGSRSV1>\r\n<STMTTRNRS>\r\n<TRNUID>0\r\n")
+	out.WriteString("<STATUS>\r\n<CODE>0\r\n<SEVERITY>INFO\r\n</STATUS>\r\n")
+	fmt.Fprintf(out, "<STMTRS>\r\n<CURDEF>%s\r\n", ofxEscaper.Replace(options.currency))
+	fmt.Fprintf(out, "<BANKACCTFROM>\r\n<BANKID>%s\r\n<ACCTID>%s\r\n<ACCTTYPE>CHECKING\r\n</BANKACCTFROM>\r\n",
+		ofxBankID, ofxEscaper.Replace(options.account(cardType)))
+
+	fmt.Fprintf(out, "<BANKTRANLIST>\r\n<DTSTART>%s\r\n<DTEND>%s\r\n", ofxDate(start), ofxDate(end))
+
+	for _, t := range transactions {
+		kind := "CREDIT"
+		if t.debit {
+			kind = "DEBIT"
+		}
+
+		out.WriteString("<STMTTRN>\r\n")
+		fmt.Fprintf(out, "<TRNTYPE>%s\r\n<DTPOSTED>%s\r\n<TRNAMT>%.2f\r\n<FITID>%s\r\n",
+			kind, ofxDate(t.date), t.amount, ofxEscaper.Replace(t.id))
+		fmt.Fprintf(out, "<NAME>%s\r\n", ofxEscaper.Replace(truncate(t.description, ofxMaxNameLength)))
+
+		if len([]rune(t.description)) > ofxMaxNameLength {
+			fmt.Fprintf(out, "<MEMO>%s\r\n", ofxEscaper.Replace(t.description))
+		}
+
+		out.WriteString("</STMTTRN>\r\n")
+	}
+
+	out.WriteString("</BANKTRANLIST>\r\n</STMTRS>\r\n</STMTTRNRS>\r\n</BANKMSGSRSV1>\r\n</OFX>\r\n")
+
+	return out.Flush()
+}
+
+func ofxDate(t time.Time) string {
+	return t.Format("20060102150405")
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+
+	return string(runes[:length])
+}
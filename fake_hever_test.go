@@ -0,0 +1,160 @@
+package gohever
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+func setupFakeHever(t *testing.T, password string) (*testutils.FakeHever, *Client) {
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username:            "TestUsername",
+		Password:            "TestPassword",
+		DeclinedCreditCards: []string{"4580000000000000"},
+		Now: func() time.Time {
+			return time.Date(2024, time.March, 14, 12, 0, 0, 0, time.UTC)
+		},
+	}).SetupTest(t)
+
+	client := NewClient(FlavorHvr(), Config{
+		Credentials: BasicCredentials("TestUsername", password),
+		CreditCards: BasicCreditCards(
+			CreditCard{Name: "declined", Number: "4580000000000000", Month: "04", Year: "2030"},
+			CreditCard{Name: "personal", Number: "45801234567899012", Month: "04", Year: "2030"},
+		),
+		BaseURL: fake.URL(),
+	})
+
+	return fake, client
+}
+
+func TestFakeHever(t *testing.T) {
+	t.Run("should fail logging in with wrong credentials", func(t *testing.T) {
+		fake, client := setupFakeHever(t, "WrongPassword")
+
+		assert.ErrorIs(t, client.Auth.Authenticate(), ErrAuthenticatedFailed)
+		assert.Equal(t, 0, fake.Logins())
+	})
+
+	t.Run("should get the card status", func(t *testing.T) {
+		fake, client := setupFakeHever(t, "TestPassword")
+
+		keva, _ := client.Card(TypeKeva)
+		status, err := keva.GetStatus()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, fake.Logins())
+		assert.Equal(t, 3000, status.MaxMonthlyAmount)
+		assert.Equal(t, 0.0, status.CurrentBalance)
+		assert.Equal(t, "11111111-2222-3333-4444-555555555555", status.SerialNumber)
+	})
+
+	t.Run("should load the card and record it in the history", func(t *testing.T) {
+		fake, client := setupFakeHever(t, "TestPassword")
+
+		keva, _ := client.Card(TypeKeva)
+		status, _ := keva.GetStatus()
+
+		result, err := keva.Load(*status, 400, WithCreditCardFallback())
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSuccess, result.Status)
+		assert.Equal(t, "personal", result.CreditCard)
+
+		assert.NoError(t, fake.Purchase(testutils.FakeCardKeva, "סופר", 150))
+
+		balance, err := keva.GetBalance()
+		assert.NoError(t, err)
+		assert.Equal(t, &CardBalance{
+			CurrentBalance:         250,
+			RemainingMonthlyAmount: 2600,
+			RemainingOnCardAmount:  750,
+		}, balance)
+
+		history, err := keva.GetHistory()
+		assert.NoError(t, err)
+		assert.Equal(t, []CardHistoryItem{
+			{Id: "1", Date: "14/03/2024", ActionType: ActionLoad, BusinessName: "טעינת כרטיס", Amount: 400},
+			{Id: "2", Date: "14/03/2024", ActionType: ActionPurchase, BusinessName: "סופר", Amount: 150},
+		}, *history)
+	})
+
+	t.Run("should block, unblock and replace the card", func(t *testing.T) {
+		fake, client := setupFakeHever(t, "TestPassword")
+
+		keva, _ := client.Card(TypeKeva)
+		status, _ := keva.GetStatus()
+
+		_, err := keva.Block()
+		assert.ErrorIs(t, err, ErrManagementNotEnabled)
+
+		client.config.EnableManagement = true
+
+		result, err := keva.Block()
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeDone, result.Outcome)
+		assert.NotEmpty(t, result.ReferenceNumber)
+
+		card, _ := fake.Card(testutils.FakeCardKeva)
+		assert.True(t, card.Blocked)
+
+		loadResult, err := keva.Load(*status, 100, WithCreditCard("personal"))
+		assert.NoError(t, err)
+		assert.Equal(t, StatusError, loadResult.Status)
+
+		result, err = keva.Block()
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeRejected, result.Outcome)
+
+		result, _ = keva.Unblock()
+		assert.Equal(t, OutcomeDone, result.Outcome)
+
+		result, _ = keva.RequestReplacement()
+		assert.Equal(t, OutcomeDone, result.Outcome)
+
+		card, _ = fake.Card(testutils.FakeCardKeva)
+		assert.True(t, card.Blocked)
+		assert.Equal(t, 1, card.Replacements)
+	})
+
+	t.Run("should not load above the limits", func(t *testing.T) {
+		fake, client := setupFakeHever(t, "TestPassword")
+
+		keva, _ := client.Card(TypeKeva)
+		status, _ := keva.GetStatus()
+
+		result, err := keva.Load(*status, 1500, WithCreditCard("personal"))
+		assert.NoError(t, err)
+		assert.Equal(t, StatusError, result.Status)
+
+		card, _ := fake.Card(testutils.FakeCardKeva)
+		assert.Equal(t, 0.0, card.Balance)
+	})
+
+	t.Run("should login again once the session expires", func(t *testing.T) {
+		fake, client := setupFakeHever(t, "TestPassword")
+
+		teamim, _ := client.Card(TypeTeamim)
+
+		_, err := teamim.GetStatus()
+		assert.NoError(t, err)
+
+		fake.ExpireSessions()
+
+		_, err = teamim.GetStatus()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, fake.Logins())
+	})
+
+	t.Run("should discover the cards of the account", func(t *testing.T) {
+		_, client := setupFakeHever(t, "TestPassword")
+
+		cards, err := client.DiscoverCards()
+
+		assert.NoError(t, err)
+		assert.Len(t, cards, 2)
+		assert.Equal(t, TypeKeva, cards[0].Type())
+		assert.Equal(t, TypeTeamim, cards[1].Type())
+	})
+}
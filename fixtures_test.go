@@ -0,0 +1,83 @@
+package gohever
+
+// Minimal responses mimicking the ones returned by HEVER. Unlike the files in ./testdata, these are
+// safe to keep in the open.
+const (
+	fixtureAuthConfig = `<html><body>
+<form id="signinForm" method="post">
+	<input type="hidden" name="bs" value="1">
+	<input type="hidden" name="cn" value="12341234134">
+	<input type="hidden" name="tmpl_filename" value="signin_hvr">
+	<input type="text" name="tz" value="">
+	<input type="password" name="password" value="">
+</form>
+<img src="acmplt.asmx/logo?t=1234123412341">
+</body></html>`
+
+	fixtureAuthSuccessful = `<html><body><div id="welcome">שלום</div></body></html>`
+
+	fixtureAuthUnsuccessful = `<html><body>
+<form id="signinForm" method="post">
+	<input type="text" name="tz" value="TestUsername">
+	<div id="msg3">פרטי ההזדהות שגויים</div>
+</form>
+</body></html>`
+
+	fixtureCardConfig = `<html><body>
+<script>
+	var gift_card_factor1 = 0.7;
+	var gift_card_factor2 = 0.8;
+	var gift_card_factor3 = 0.9;
+	var gift_card_factor1_price = 1000;
+	var gift_card_factor2_price = 1500;
+	var gift_card_factor3_price = 2000;
+	var max_month_load = 4500;
+	var max_on_card = 1000;
+</script>
+<form method="post"><input type="hidden" name="sn" value="12345678-9abc-def1-2345-6789abcdef12"></form>
+</body></html>`
+
+	fixtureVoucherConfig = `<html><body>
+<script>
+	var voucher_factor1 = 0.85;
+	var voucher_factor2 = 0.9;
+	var voucher_factor3 = 0.95;
+	var voucher_factor1_price = 500;
+	var voucher_factor2_price = 500;
+	var voucher_factor3_price = 1000;
+	var max_month_load = 2000;
+	var max_on_card = 1000;
+</script>
+<form method="post"><input type="hidden" name="sn" value="87654321-9abc-def1-2345-6789abcdef12"></form>
+</body></html>`
+
+	fixtureCardBalance = `512 | 3,988 | 488`
+
+	fixtureLoadSuccess = `<html><body>
+<div id="msg_ok">בקשת טעינת הכרטיס בוצעה. מספר ההזמנה: 12344321</div>
+<script>if ( 2 == 1 ) { show_msg(); }</script>
+</body></html>`
+
+	fixtureManageConfirm = `<html><body>
+<div id="confirm_msg">נא לאשר את הפעולה</div>
+<form method="post"><input type="hidden" name="confirm_token" value="a1b2c3d4"></form>
+</body></html>`
+
+	fixtureManageDone = `<html><body>
+<div id="msg_ok">הבקשה התקבלה. מספר פנייה: 55667788</div>
+</body></html>`
+
+	fixtureManageRejected = `<html><body>
+<table class="table" bgcolor="red"><tr><td>הכרטיס כבר חסום</td></tr></table>
+</body></html>`
+
+	fixtureLoadDeclined = `<html><body>
+<table class="table" bgcolor="red"><tr><td>כרטיס האשראי נדחה</td></tr></table>
+<script>if ( 1 == 1 ) { show_msg(); }</script>
+</body></html>`
+
+	fixtureLoadAboveLimit = `<html><body>
+<table class="table" bgcolor="red"><tr><td>חריגה ממגבלת הטעינה החודשית</td></tr></table>
+<script>if ( 1 == 1 ) { show_msg(); }</script>
+</body></html>`
+)
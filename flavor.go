@@ -0,0 +1,171 @@
+package gohever
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Describes a site running the HEVER platform. Sites sharing the platform differ in where they're
+// served from, in the cards they sell and sometimes in the markup of their pages.
+type Flavor struct {
+	// A short name identifying the flavor, e.g. "hvr"
+	Name string
+
+	BaseURL string
+
+	// Paths relative to BaseURL. Empty paths fall back to the ones of HEVER.
+	LoginPath  string
+	LogoutPath string
+
+	// The page the site redirects to once the session has expired
+	SessionExpiredPath string
+
+	// The card products sold on the site
+	Products []CardProduct
+
+	// Overrides for the selectors used for reading the site's pages
+	Selectors Selectors
+}
+
+// CSS selectors for reading the site's pages. Empty selectors fall back to the ones of HEVER.
+type Selectors struct {
+	// The login form, which hidden inputs are sent back when logging in
+	LoginForm string
+
+	// An element which only shows up when the login has failed
+	LoginFailed string
+
+	// The rows of the card history table
+	HistoryRows string
+
+	// The message shown after a successful load or card management action
+	LoadMessage string
+
+	// The error shown after a failed load or card management action
+	LoadError string
+}
+
+const urlSessionExpired = "logout.aspx"
+
+var defaultSelectors = Selectors{
+	LoginForm:   "form#signinForm",
+	LoginFailed: "#msg3",
+	HistoryRows: "tr.historyRows[id]",
+	LoadMessage: "div#msg_ok",
+	LoadError:   "table.table[bgcolor=red]",
+}
+
+// The flavors known to this package. They're only handed out as copies, see FlavorHvr and FlavorMcc.
+var (
+	flavorHvr = &Flavor{
+		Name:     "hvr",
+		BaseURL:  heverBaseUrl,
+		Products: []CardProduct{ProductKeva, ProductTeamim},
+	}
+
+	flavorMcc = &Flavor{
+		Name:     "mcc",
+		BaseURL:  mccBaseUrl,
+		Products: []CardProduct{ProductSheli},
+	}
+)
+
+var (
+	flavorsMu sync.RWMutex
+	flavors   = map[string]*Flavor{
+		flavorHvr.Name: flavorHvr,
+		flavorMcc.Name: flavorMcc,
+	}
+)
+
+// Returns a copy of the flavor of HEVER
+func FlavorHvr() *Flavor {
+	return flavorHvr.clone()
+}
+
+// Returns a copy of the flavor of MCC
+func FlavorMcc() *Flavor {
+	return flavorMcc.clone()
+}
+
+// Registers a custom flavor, so it could be looked up by its name using LookupFlavor. The flavor is
+// copied, so changing it afterwards has no effect.
+func RegisterFlavor(flavor *Flavor) error {
+	if flavor == nil || flavor.Name == "" || flavor.BaseURL == "" {
+		return ErrInvalidFlavor
+	}
+
+	flavorsMu.Lock()
+	defer flavorsMu.Unlock()
+
+	if _, exists := flavors[flavor.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrFlavorAlreadyRegistered, flavor.Name)
+	}
+
+	flavors[flavor.Name] = flavor.clone()
+	return nil
+}
+
+// Returns a copy of the flavor registered with the given name
+func LookupFlavor(name string) (*Flavor, bool) {
+	flavorsMu.RLock()
+	defer flavorsMu.RUnlock()
+
+	flavor, ok := flavors[name]
+	if !ok {
+		return nil, false
+	}
+
+	return flavor.clone(), true
+}
+
+// Returns a deep copy of the flavor, so changing the copy won't change the original
+func (flavor *Flavor) clone() *Flavor {
+	clone := *flavor
+	clone.Products = slices.Clone(flavor.Products)
+
+	for i := range clone.Products {
+		clone.Products[i].QueryParams = maps.Clone(clone.Products[i].QueryParams)
+	}
+
+	return &clone
+}
+
+func (flavor *Flavor) String() string {
+	return flavor.Name
+}
+
+func (flavor *Flavor) loginPath() string {
+	return orDefault(flavor.LoginPath, urlAuthenticate)
+}
+
+func (flavor *Flavor) logoutPath() string {
+	return orDefault(flavor.LogoutPath, urlDeauthenticate)
+}
+
+func (flavor *Flavor) sessionExpiredPath() string {
+	return orDefault(flavor.SessionExpiredPath, urlSessionExpired)
+}
+
+// Returns the selectors of the flavor, with the defaults filled in
+func (flavor *Flavor) selectors() Selectors {
+	overrides := flavor.Selectors
+
+	return Selectors{
+		LoginForm:   orDefault(overrides.LoginForm, defaultSelectors.LoginForm),
+		LoginFailed: orDefault(overrides.LoginFailed, defaultSelectors.LoginFailed),
+		HistoryRows: orDefault(overrides.HistoryRows, defaultSelectors.HistoryRows),
+		LoadMessage: orDefault(overrides.LoadMessage, defaultSelectors.LoadMessage),
+		LoadError:   orDefault(overrides.LoadError, defaultSelectors.LoadError),
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
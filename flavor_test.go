@@ -0,0 +1,138 @@
+package gohever
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+func TestRegisterFlavor(t *testing.T) {
+	t.Run("should lookup the built-in flavors", func(t *testing.T) {
+		flavor, ok := LookupFlavor("hvr")
+		assert.True(t, ok)
+		assert.Equal(t, FlavorHvr(), flavor)
+
+		flavor, ok = LookupFlavor("mcc")
+		assert.True(t, ok)
+		assert.Equal(t, FlavorMcc(), flavor)
+
+		_, ok = LookupFlavor("nope")
+		assert.False(t, ok)
+	})
+
+	t.Run("should register a custom flavor", func(t *testing.T) {
+		staging := &Flavor{
+			Name:     "hvr-staging-register",
+			BaseURL:  "https://staging.hvr.example/",
+			Products: FlavorHvr().Products,
+		}
+
+		assert.NoError(t, RegisterFlavor(staging))
+
+		flavor, ok := LookupFlavor("hvr-staging-register")
+		assert.True(t, ok)
+		assert.Equal(t, staging, flavor)
+
+		assert.ErrorIs(t, RegisterFlavor(staging), ErrFlavorAlreadyRegistered)
+	})
+
+	t.Run("should only hand out copies of the flavors", func(t *testing.T) {
+		FlavorHvr().BaseURL = "https://changed.example/"
+		FlavorHvr().Products[1].QueryParams[queryParamFoodCard] = "0"
+
+		flavor, _ := LookupFlavor("hvr")
+		flavor.Products = nil
+
+		staging := &Flavor{Name: "hvr-staging-copy", BaseURL: "https://staging.hvr.example/"}
+		assert.NoError(t, RegisterFlavor(staging))
+		staging.BaseURL = "https://changed.example/"
+
+		client := NewClient(FlavorHvr(), Config{})
+		client.Flavor().Name = "changed"
+
+		assert.Equal(t, &Flavor{
+			Name:     "hvr",
+			BaseURL:  heverBaseUrl,
+			Products: []CardProduct{ProductKeva, ProductTeamim},
+		}, FlavorHvr())
+		assert.Equal(t, "hvr", client.Flavor().Name)
+		assert.Len(t, client.Cards(), 2)
+
+		flavor, _ = LookupFlavor("hvr-staging-copy")
+		assert.Equal(t, "https://staging.hvr.example/", flavor.BaseURL)
+	})
+
+	t.Run("should not register invalid flavors", func(t *testing.T) {
+		assert.ErrorIs(t, RegisterFlavor(nil), ErrInvalidFlavor)
+		assert.ErrorIs(t, RegisterFlavor(&Flavor{Name: "no-base-url"}), ErrInvalidFlavor)
+		assert.ErrorIs(t, RegisterFlavor(&Flavor{BaseURL: "https://no.name/"}), ErrInvalidFlavor)
+	})
+}
+
+func TestCustomFlavor(t *testing.T) {
+	portal := &Flavor{
+		Name:               "portal",
+		BaseURL:            "https://portal.example/",
+		LoginPath:          "login.aspx",
+		LogoutPath:         "logout",
+		SessionExpiredPath: "expired.aspx",
+		Products: []CardProduct{
+			{Type: TypeKeva, Path: "orders/card.aspx"},
+		},
+		Selectors: Selectors{
+			LoginFailed: "div.login-error",
+			HistoryRows: "tr.row[id]",
+		},
+	}
+
+	t.Run("should setup the cards of the flavor", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{Flavor: portal})
+
+		assert.Len(t, client.Cards(), 1)
+
+		card, err := client.Card(TypeKeva)
+		assert.NoError(t, err)
+		assert.Equal(t, "orders/card.aspx", card.(*Card).Product().Path)
+
+		_, err = client.Card(TypeTeamim)
+		assert.ErrorIs(t, err, ErrCardNotSupported)
+	})
+
+	t.Run("should login using the flavor paths and selectors", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Flavor: portal,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/login.aspx").Once().Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Once().Status(200),
+				testutils.NewMockedRequest("POST", "/login.aspx").Once().Status(200).Body(`<div class="login-error">nope</div>`),
+			},
+		})
+
+		assert.ErrorIs(t, client.Auth.Authenticate(), ErrAuthenticatedFailed)
+	})
+
+	t.Run("should detect session expiry using the flavor paths", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Flavor:        portal,
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/card.aspx").Once().Status(302).Header("Location", "/expired.aspx"),
+				testutils.NewMockedRequest("GET", "/login.aspx").Once().Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Once().Status(200),
+				testutils.NewMockedRequest("POST", "/login.aspx").Once().Status(200).Body(fixtureAuthSuccessful),
+				testutils.NewMockedRequest("GET", "/orders/card.aspx").Once().Status(200).Body(`<table>
+<tr class="row" id="1"><td>01/01/2024</td><td>רכישה</td><td>Shop</td><td>12.5</td></tr>
+</table>`),
+			},
+		})
+
+		card, _ := client.Card(TypeKeva)
+		history, err := card.GetHistory()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []CardHistoryItem{
+			{Id: "1", Date: "01/01/2024", ActionType: ActionPurchase, BusinessName: "Shop", Amount: 12.5},
+		}, *history)
+	})
+}
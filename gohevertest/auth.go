@@ -0,0 +1,67 @@
+package gohevertest
+
+import (
+	"sync"
+
+	"github.com/yardnsm/gohever"
+)
+
+// An in-memory fake of gohever.AuthInterface, keeping track of the logins
+type Auth struct {
+	mu            sync.Mutex
+	authenticated bool
+	logins        int
+	err           error
+}
+
+var _ gohever.AuthInterface = (*Auth)(nil)
+
+func NewAuth() *Auth {
+	return &Auth{}
+}
+
+// Makes the logins fail with err, until it's set back to nil
+func (auth *Auth) SetError(err error) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	auth.err = err
+}
+
+func (auth *Auth) Authenticate() error {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	if auth.err != nil {
+		return auth.err
+	}
+
+	auth.authenticated = true
+	auth.logins++
+
+	return nil
+}
+
+func (auth *Auth) Deauthenticate() error {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	auth.authenticated = false
+	return nil
+}
+
+// Returns whether logged in
+func (auth *Auth) Authenticated() bool {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	return auth.authenticated
+}
+
+// Returns the number of successful logins so far
+func (auth *Auth) Logins() int {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	return auth.logins
+}
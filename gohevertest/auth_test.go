@@ -0,0 +1,23 @@
+package gohevertest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+)
+
+func TestAuth(t *testing.T) {
+	auth := NewAuth()
+
+	assert.NoError(t, auth.Authenticate())
+	assert.True(t, auth.Authenticated())
+	assert.Equal(t, 1, auth.Logins())
+
+	assert.NoError(t, auth.Deauthenticate())
+	assert.False(t, auth.Authenticated())
+
+	auth.SetError(gohever.ErrAuthenticatedFailed)
+	assert.ErrorIs(t, auth.Authenticate(), gohever.ErrAuthenticatedFailed)
+	assert.Equal(t, 1, auth.Logins())
+}
@@ -0,0 +1,362 @@
+// Package gohevertest provides in-memory fakes of the gohever interfaces, for testing code using
+// gohever without making any HTTP requests.
+package gohevertest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yardnsm/gohever"
+)
+
+// The operations of a card, used for injecting errors
+type Operation int
+
+const (
+	OpGetStatus Operation = iota
+	OpGetBalance
+	OpGetHistory
+	OpLoad
+	OpBlock
+	OpUnblock
+	OpRequestReplacement
+)
+
+// A load applied to a fake card
+type Load struct {
+	Amount   int32
+	Estimate gohever.CardEstimate
+}
+
+// An in-memory fake of gohever.CardInterface. Loads are applied to the balance using
+// CardStatus.Estimate, so they're subject to the same limits as the real cards.
+type Card struct {
+	// Used for dating the history items, time.Now is used when nil
+	Now func() time.Time
+
+	mu           sync.Mutex
+	cardType     gohever.CardType
+	status       gohever.CardStatus
+	blocked      bool
+	history      []gohever.CardHistoryItem
+	loads        []Load
+	requests     int
+	replacements int
+	errs         map[Operation]error
+	declined     string
+}
+
+var _ gohever.CardInterface = (*Card)(nil)
+
+// Creates a fake card with the given initial status
+func NewCard(cardType gohever.CardType, status gohever.CardStatus) *Card {
+	return &Card{
+		cardType: cardType,
+		status:   status,
+		errs:     make(map[Operation]error),
+	}
+}
+
+// Returns the status of an empty card with the common factors and limits of a HEVER card
+func DefaultStatus() gohever.CardStatus {
+	return gohever.CardStatus{
+		Factors: []gohever.CardFactor{
+			{Factor: 0.7, Amount: 1000},
+			{Factor: 0.75, Amount: 1000},
+			{Factor: 0.8, Amount: 1000},
+		},
+
+		MaxMonthlyAmount: 3000,
+		MaxOnCardAmount:  1000,
+
+		RemainingMonthlyAmount: 3000,
+		RemainingOnCardAmount:  1000,
+
+		SerialNumber: "00000000-0000-0000-0000-000000000000",
+	}
+}
+
+// Makes the given operation fail with err, until it's set back to nil
+func (card *Card) SetError(op Operation, err error) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if err == nil {
+		delete(card.errs, op)
+		return
+	}
+
+	card.errs[op] = err
+}
+
+// Makes the loads get declined with the given message, until it's set back to an empty one
+func (card *Card) DeclineLoads(message string) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	card.declined = message
+}
+
+// Makes a purchase using the card, as if it was used in the given business
+func (card *Card) Purchase(businessName string, amount float64) error {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if amount > card.status.CurrentBalance {
+		return fmt.Errorf("not enough balance on card: %v", card.status.CurrentBalance)
+	}
+
+	card.status.CurrentBalance -= amount
+	card.status.RemainingOnCardAmount += amount
+	card.appendHistory(gohever.ActionPurchase, businessName, amount)
+
+	return nil
+}
+
+// Returns the loads applied to the card so far
+func (card *Card) Loads() []Load {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	return append([]Load(nil), card.loads...)
+}
+
+func (card *Card) appendHistory(action gohever.CardAction, businessName string, amount float64) {
+	now := time.Now
+	if card.Now != nil {
+		now = card.Now
+	}
+
+	card.history = append(card.history, gohever.CardHistoryItem{
+		Id:           strconv.Itoa(len(card.history) + 1),
+		Date:         now().Format("02/01/2006"),
+		ActionType:   action,
+		BusinessName: businessName,
+		Amount:       amount,
+	})
+}
+
+func (card *Card) Type() gohever.CardType {
+	return card.cardType
+}
+
+func (card *Card) GetStatus() (*gohever.CardStatus, error) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if err := card.errs[OpGetStatus]; err != nil {
+		return nil, err
+	}
+
+	status := card.status
+	status.Factors = append([]gohever.CardFactor(nil), card.status.Factors...)
+
+	return &status, nil
+}
+
+func (card *Card) GetBalance(opts ...gohever.StatusOption) (*gohever.CardBalance, error) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if err := card.errs[OpGetBalance]; err != nil {
+		return nil, err
+	}
+
+	return &gohever.CardBalance{
+		CurrentBalance:         card.status.CurrentBalance,
+		RemainingMonthlyAmount: card.status.RemainingMonthlyAmount,
+		RemainingOnCardAmount:  card.status.RemainingOnCardAmount,
+	}, nil
+}
+
+func (card *Card) GetHistory() (*[]gohever.CardHistoryItem, error) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if err := card.errs[OpGetHistory]; err != nil {
+		return nil, err
+	}
+
+	history := append([]gohever.CardHistoryItem(nil), card.history...)
+	return &history, nil
+}
+
+// Loads the card. Like the site, loads above the limits, with a wrong serial number or into a
+// blocked card result in gohever.StatusError rather than an error.
+func (card *Card) Load(status gohever.CardStatus, amount int32, opts ...gohever.LoadOption) (*gohever.LoadResult, error) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if err := card.errs[OpLoad]; err != nil {
+		return nil, err
+	}
+
+	if card.declined != "" {
+		return &gohever.LoadResult{Status: gohever.StatusError, RawMessage: card.declined, Declined: true}, nil
+	}
+
+	if card.blocked {
+		return &gohever.LoadResult{Status: gohever.StatusError, RawMessage: "card is blocked"}, nil
+	}
+
+	if status.SerialNumber != card.status.SerialNumber {
+		return &gohever.LoadResult{Status: gohever.StatusError, RawMessage: "card was not found"}, nil
+	}
+
+	estimate, err := card.status.Estimate(float64(amount))
+	if err != nil {
+		return &gohever.LoadResult{Status: gohever.StatusError, RawMessage: err.Error()}, nil
+	}
+
+	loaded := float64(amount)
+
+	card.status.CurrentBalance += loaded
+	card.status.RemainingOnCardAmount -= loaded
+	card.status.RemainingMonthlyAmount -= loaded
+	card.status.Leftovers -= estimate.Leftovers
+
+	if card.status.MaxMonthlyAmount > 0 {
+		card.status.MonthlyUsage = 1 - card.status.RemainingMonthlyAmount/float64(card.status.MaxMonthlyAmount)
+	}
+
+	card.loads = append(card.loads, Load{Amount: amount, Estimate: *estimate})
+	card.appendHistory(gohever.ActionLoad, "", loaded)
+
+	return &gohever.LoadResult{
+		Status:     gohever.StatusSuccess,
+		LoadNumber: strconv.Itoa(10000000 + len(card.loads)),
+	}, nil
+}
+
+// Whether the card is blocked
+func (card *Card) Blocked() bool {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	return card.blocked
+}
+
+// Returns the number of replacements ordered for the card so far
+func (card *Card) Replacements() int {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	return card.replacements
+}
+
+// Applies a management action. Like the site, actions which can't be done in the current state of
+// the card result in gohever.OutcomeRejected rather than an error.
+func (card *Card) manage(op Operation, action gohever.ManageAction, apply func() string) (*gohever.ManageResult, error) {
+	card.mu.Lock()
+	defer card.mu.Unlock()
+
+	if err := card.errs[op]; err != nil {
+		return nil, err
+	}
+
+	if rejection := apply(); rejection != "" {
+		return &gohever.ManageResult{Action: action, Outcome: gohever.OutcomeRejected, RawMessage: rejection}, nil
+	}
+
+	card.requests++
+
+	return &gohever.ManageResult{
+		Action:          action,
+		Outcome:         gohever.OutcomeDone,
+		ReferenceNumber: strconv.Itoa(20000000 + card.requests),
+	}, nil
+}
+
+func (card *Card) Block() (*gohever.ManageResult, error) {
+	return card.manage(OpBlock, gohever.ManageBlock, func() string {
+		if card.blocked {
+			return "card is already blocked"
+		}
+
+		card.blocked = true
+		return ""
+	})
+}
+
+func (card *Card) Unblock() (*gohever.ManageResult, error) {
+	return card.manage(OpUnblock, gohever.ManageUnblock, func() string {
+		if !card.blocked {
+			return "card is not blocked"
+		}
+
+		card.blocked = false
+		return ""
+	})
+}
+
+// Orders a replacement, blocking the card like the site does
+func (card *Card) RequestReplacement() (*gohever.ManageResult, error) {
+	return card.manage(OpRequestReplacement, gohever.ManageReplace, func() string {
+		card.blocked = true
+		card.replacements++
+
+		return ""
+	})
+}
+
+// The Context variants fail once the context is done, and are otherwise the same as the methods
+// without a context
+
+func (card *Card) GetStatusContext(ctx context.Context) (*gohever.CardStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.GetStatus()
+}
+
+func (card *Card) GetBalanceContext(ctx context.Context, opts ...gohever.StatusOption) (*gohever.CardBalance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.GetBalance(opts...)
+}
+
+func (card *Card) GetHistoryContext(ctx context.Context) (*[]gohever.CardHistoryItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.GetHistory()
+}
+
+func (card *Card) LoadContext(ctx context.Context, status gohever.CardStatus, amount int32, opts ...gohever.LoadOption) (*gohever.LoadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.Load(status, amount, opts...)
+}
+
+func (card *Card) BlockContext(ctx context.Context) (*gohever.ManageResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.Block()
+}
+
+func (card *Card) UnblockContext(ctx context.Context) (*gohever.ManageResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.Unblock()
+}
+
+func (card *Card) RequestReplacementContext(ctx context.Context) (*gohever.ManageResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return card.RequestReplacement()
+}
@@ -0,0 +1,176 @@
+package gohevertest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+)
+
+func setupCard() *Card {
+	card := NewCard(gohever.TypeKeva, DefaultStatus())
+	card.Now = func() time.Time {
+		return time.Date(2024, time.March, 14, 12, 0, 0, 0, time.UTC)
+	}
+
+	return card
+}
+
+func TestCardLoad(t *testing.T) {
+	t.Run("should apply the load to the balance", func(t *testing.T) {
+		card := setupCard()
+		status, _ := card.GetStatus()
+
+		result, err := card.Load(*status, 400)
+		assert.NoError(t, err)
+		assert.Equal(t, gohever.StatusSuccess, result.Status)
+		assert.Equal(t, "10000001", result.LoadNumber)
+
+		balance, _ := card.GetBalance()
+		assert.Equal(t, &gohever.CardBalance{
+			CurrentBalance:         400,
+			RemainingMonthlyAmount: 2600,
+			RemainingOnCardAmount:  600,
+		}, balance)
+
+		loads := card.Loads()
+		assert.Len(t, loads, 1)
+		assert.Equal(t, 280.0, loads[0].Estimate.TotalFactored)
+
+		status, _ = card.GetStatus()
+		assert.InDelta(t, 400.0/3000, status.MonthlyUsage, 0.0001)
+	})
+
+	t.Run("should enforce the limits", func(t *testing.T) {
+		card := setupCard()
+		status, _ := card.GetStatus()
+
+		result, err := card.Load(*status, 1500)
+		assert.NoError(t, err)
+		assert.Equal(t, gohever.StatusError, result.Status)
+		assert.Equal(t, gohever.ErrLoadAboveOnCardLimit.Error(), result.RawMessage)
+
+		assert.Empty(t, card.Loads())
+	})
+
+	t.Run("should not load with a wrong serial number", func(t *testing.T) {
+		card := setupCard()
+
+		result, err := card.Load(gohever.CardStatus{SerialNumber: "nope"}, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, gohever.StatusError, result.Status)
+	})
+
+	t.Run("should decline loads", func(t *testing.T) {
+		card := setupCard()
+		status, _ := card.GetStatus()
+
+		card.DeclineLoads("declined")
+
+		result, _ := card.Load(*status, 100)
+		assert.Equal(t, gohever.StatusError, result.Status)
+		assert.Equal(t, "declined", result.RawMessage)
+		assert.True(t, result.Declined)
+
+		card.DeclineLoads("")
+
+		result, _ = card.Load(*status, 100)
+		assert.Equal(t, gohever.StatusSuccess, result.Status)
+	})
+}
+
+func TestCardHistory(t *testing.T) {
+	card := setupCard()
+	status, _ := card.GetStatus()
+
+	card.Load(*status, 400)
+
+	assert.NoError(t, card.Purchase("סופר", 150))
+	assert.Error(t, card.Purchase("סופר", 1000))
+
+	history, err := card.GetHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, []gohever.CardHistoryItem{
+		{Id: "1", Date: "14/03/2024", ActionType: gohever.ActionLoad, Amount: 400},
+		{Id: "2", Date: "14/03/2024", ActionType: gohever.ActionPurchase, BusinessName: "סופר", Amount: 150},
+	}, *history)
+
+	balance, _ := card.GetBalance()
+	assert.Equal(t, 250.0, balance.CurrentBalance)
+	assert.Equal(t, 750.0, balance.RemainingOnCardAmount)
+}
+
+func TestCardManage(t *testing.T) {
+	t.Run("should block and unblock the card", func(t *testing.T) {
+		card := setupCard()
+		status, _ := card.GetStatus()
+
+		result, err := card.Block()
+		assert.NoError(t, err)
+		assert.Equal(t, &gohever.ManageResult{
+			Action:          gohever.ManageBlock,
+			Outcome:         gohever.OutcomeDone,
+			ReferenceNumber: "20000001",
+		}, result)
+
+		assert.True(t, card.Blocked())
+
+		loadResult, _ := card.Load(*status, 100)
+		assert.Equal(t, gohever.StatusError, loadResult.Status)
+
+		result, _ = card.Block()
+		assert.Equal(t, gohever.OutcomeRejected, result.Outcome)
+
+		result, _ = card.Unblock()
+		assert.Equal(t, gohever.OutcomeDone, result.Outcome)
+
+		result, _ = card.Unblock()
+		assert.Equal(t, gohever.OutcomeRejected, result.Outcome)
+
+		loadResult, _ = card.Load(*status, 100)
+		assert.Equal(t, gohever.StatusSuccess, loadResult.Status)
+	})
+
+	t.Run("should order a replacement", func(t *testing.T) {
+		card := setupCard()
+
+		result, err := card.RequestReplacement()
+		assert.NoError(t, err)
+		assert.Equal(t, gohever.OutcomeDone, result.Outcome)
+		assert.Equal(t, 1, card.Replacements())
+		assert.True(t, card.Blocked())
+	})
+
+	t.Run("should fail with the set error", func(t *testing.T) {
+		card := setupCard()
+		card.SetError(OpBlock, errors.New("oops"))
+
+		_, err := card.Block()
+		assert.Error(t, err)
+		assert.False(t, card.Blocked())
+	})
+}
+
+func TestCardErrors(t *testing.T) {
+	card := setupCard()
+	errOops := errors.New("oops")
+
+	card.SetError(OpGetStatus, errOops)
+	card.SetError(OpLoad, errOops)
+
+	_, err := card.GetStatus()
+	assert.ErrorIs(t, err, errOops)
+
+	_, err = card.Load(DefaultStatus(), 100)
+	assert.ErrorIs(t, err, errOops)
+
+	_, err = card.GetBalance()
+	assert.NoError(t, err)
+
+	card.SetError(OpGetStatus, nil)
+
+	_, err = card.GetStatus()
+	assert.NoError(t, err)
+}
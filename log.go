@@ -0,0 +1,34 @@
+package gohever
+
+import (
+	"context"
+	"log/slog"
+)
+
+// A slog.Handler dropping all records, used when no logger was configured
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+func newLogger(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(discardHandler{})
+	}
+
+	return logger
+}
+
+// Errors may contain parts of the responses, so they should be redacted before being logged
+func errorAttr(err error) slog.Attr {
+	return slog.String("error", redact(err.Error()))
+}
+
+func (hvr *Client) logParseFailure(ctx context.Context, endpoint string, err error) {
+	hvr.logger.LogAttrs(ctx, slog.LevelError, "failed to parse response",
+		slog.String("endpoint", endpoint),
+		errorAttr(err),
+	)
+}
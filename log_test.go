@@ -0,0 +1,169 @@
+package gohever
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+// Sets up a logger writing JSON records into a buffer, and returns a function parsing them
+func setupTestLogger(client *Client) func() []map[string]interface{} {
+	var buffer bytes.Buffer
+
+	client.logger = slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	return func() []map[string]interface{} {
+		var records []map[string]interface{}
+
+		decoder := json.NewDecoder(&buffer)
+		for decoder.More() {
+			var record map[string]interface{}
+			if err := decoder.Decode(&record); err != nil {
+				panic(err)
+			}
+
+			records = append(records, record)
+		}
+
+		return records
+	}
+}
+
+// Returns the messages of the given records
+func recordMessages(records []map[string]interface{}) []string {
+	var messages []string
+	for _, record := range records {
+		messages = append(messages, record["msg"].(string))
+	}
+
+	return messages
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("should not log anything by default", func(t *testing.T) {
+		client := NewClient(FlavorHvr(), Config{})
+
+		assert.False(t, client.logger.Enabled(context.Background(), slog.LevelError))
+	})
+
+	t.Run("should log a successful authentication", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthSuccessful),
+			},
+		})
+
+		records := setupTestLogger(client)
+
+		assert.NoError(t, client.Auth.Authenticate())
+
+		logged := records()
+		assert.Equal(t, []string{
+			"authentication started",
+			"endpoint call",
+			"endpoint call",
+			"endpoint call",
+			"authenticated",
+		}, recordMessages(logged))
+
+		assert.Equal(t, "auth_config", logged[1]["endpoint"])
+		assert.Equal(t, "GET", logged[1]["method"])
+		assert.EqualValues(t, 200, logged[1]["status"])
+		assert.Contains(t, logged[1], "duration")
+
+		assert.Equal(t, "authenticate", logged[3]["endpoint"])
+		assert.Equal(t, "POST", logged[3]["method"])
+	})
+
+	t.Run("should log a failed authentication", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthUnsuccessful),
+			},
+		})
+
+		records := setupTestLogger(client)
+
+		assert.ErrorIs(t, client.Auth.Authenticate(), ErrAuthenticatedFailed)
+
+		logged := records()
+		last := logged[len(logged)-1]
+
+		assert.Equal(t, "authentication failed", last["msg"])
+		assert.Equal(t, "WARN", last["level"])
+		assert.Equal(t, ErrAuthenticatedFailed.Error(), last["error"])
+	})
+
+	t.Run("should log an expired session", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/loggedOut").Status(302).Header("Location", "/logout.aspx"),
+			},
+		})
+
+		records := setupTestLogger(client)
+
+		client.newRequest().Get("loggedOut")
+
+		logged := records()
+		assert.Equal(t, []string{"session expired"}, recordMessages(logged))
+	})
+
+	t.Run("should log parse failures", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body("<html>oops</html>"),
+			},
+		})
+
+		records := setupTestLogger(client)
+
+		_, err := newCard(client, TypeKeva).GetStatus()
+		assert.ErrorIs(t, err, ErrUnableToParseCardBalance)
+
+		logged := records()
+		last := logged[len(logged)-1]
+
+		assert.Equal(t, "failed to parse response", last["msg"])
+		assert.Equal(t, "ERROR", last["level"])
+		assert.Equal(t, "card_balance", last["endpoint"])
+		assert.Equal(t, ErrUnableToParseCardBalance.Error(), last["error"])
+	})
+
+	t.Run("should redact sensitive fields", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).
+					Body("password=TestPassword | 3,988 | 488"),
+			},
+		})
+
+		records := setupTestLogger(client)
+
+		_, err := newCard(client, TypeKeva).GetStatus()
+		assert.Error(t, err)
+
+		logged := records()
+		last := logged[len(logged)-1]
+
+		assert.Equal(t, "failed to parse response", last["msg"])
+		assert.NotContains(t, last["error"], "TestPassword")
+		assert.Contains(t, last["error"], "[REDACTED]")
+	})
+}
@@ -0,0 +1,213 @@
+package gohever
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var regexConfirmToken = regexp.MustCompile("name=\"confirm_token\" value=\"(\\w+)\"")
+
+// Card management actions, all of which require Config.EnableManagement
+type ManageAction int
+
+const (
+	ManageBlock ManageAction = iota
+	ManageUnblock
+	ManageReplace
+)
+
+func (action ManageAction) String() string {
+	switch action {
+	case ManageBlock:
+		return "block"
+	case ManageUnblock:
+		return "unblock"
+	case ManageReplace:
+		return "replace"
+	}
+
+	return "unknown"
+}
+
+// The outcome of a card management action
+type ManageOutcome int
+
+const (
+	OutcomeNone ManageOutcome = iota
+	OutcomeRejected
+	OutcomeDone
+)
+
+func (outcome ManageOutcome) String() string {
+	switch outcome {
+	case OutcomeNone:
+		return "none"
+	case OutcomeRejected:
+		return "rejected"
+	case OutcomeDone:
+		return "done"
+	}
+
+	return "unknown"
+}
+
+type ManageResult struct {
+	Action  ManageAction  `json:"action"`
+	Outcome ManageOutcome `json:"outcome"`
+
+	// The number of the request, given by the site once it's done
+	ReferenceNumber string `json:"reference_number"`
+	RawMessage      string `json:"raw_message"`
+}
+
+// Parses the confirmation page of an action. The site shows an error rather than a confirmation
+// when the action can't be done, e.g. when blocking a blocked card.
+func parseManageRequestResponse(resp *resty.Response, action ManageAction, selectors Selectors) (string, *ManageResult, error) {
+	body := string(resp.Body())
+
+	if matches := regexConfirmToken.FindStringSubmatch(body); matches != nil {
+		return matches[1], nil, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if rejection := doc.Find(selectors.LoadError); rejection.Length() > 0 {
+		return "", &ManageResult{
+			Action:     action,
+			Outcome:    OutcomeRejected,
+			RawMessage: strings.TrimSpace(rejection.Text()),
+		}, nil
+	}
+
+	return "", nil, ErrUnableToParseConfirmation
+}
+
+func parseManageConfirmResponse(resp *resty.Response, action ManageAction, selectors Selectors) (*ManageResult, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(resp.Body())))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ManageResult{Action: action}
+
+	if message := doc.Find(selectors.LoadMessage); message.Length() > 0 {
+		result.Outcome = OutcomeDone
+		result.RawMessage = strings.TrimSpace(message.Text())
+		result.ReferenceNumber = regexPlainNumber.FindString(result.RawMessage)
+	} else if rejection := doc.Find(selectors.LoadError); rejection.Length() > 0 {
+		result.Outcome = OutcomeRejected
+		result.RawMessage = strings.TrimSpace(rejection.Text())
+	} else {
+		return nil, ErrUnableToParseConfirmation
+	}
+
+	return result, nil
+}
+
+// Asks the site for the action, getting the token for confirming it
+func (card *Card) requestAction(ctx context.Context, action ManageAction) (string, *ManageResult, error) {
+	req := card.buildBaseRequest().
+		SetFormData(formData{
+			"om":      action.String(),
+			"confirm": "0",
+		})
+
+	resp, err := card.hvr.execute(ctx, endpointManageRequest, req, resty.MethodPost, card.product.Path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, result, err := parseManageRequestResponse(resp, action, card.hvr.selectors)
+	if err != nil {
+		card.hvr.logParseFailure(ctx, endpointManageRequest, err)
+		return "", nil, err
+	}
+
+	return token, result, nil
+}
+
+func (card *Card) confirmAction(ctx context.Context, action ManageAction, token string) (*ManageResult, error) {
+	req := card.buildBaseRequest().
+		SetFormData(formData{
+			"om":            action.String(),
+			"confirm":       "1",
+			"confirm_token": token,
+		})
+
+	resp, err := card.hvr.execute(ctx, endpointManageConfirm, req, resty.MethodPost, card.product.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseManageConfirmResponse(resp, action, card.hvr.selectors)
+	if err != nil {
+		card.hvr.logParseFailure(ctx, endpointManageConfirm, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (card *Card) manage(ctx context.Context, action ManageAction) (result *ManageResult, err error) {
+	if !card.hvr.config.EnableManagement {
+		return nil, ErrManagementNotEnabled
+	}
+
+	ctx, span := card.hvr.telemetry.startSpan(ctx, "Card.Manage",
+		attribute.Stringer("gohever.card.type", card.cardType),
+		attribute.Stringer("gohever.manage.action", action))
+	defer func() { endSpan(span, err) }()
+
+	// Both steps are retried together, as the token is bound to the session
+	result, err = wrapAuthenticated(ctx, card.hvr, func() (*ManageResult, error) {
+		token, rejected, err := card.requestAction(ctx, action)
+		if err != nil || rejected != nil {
+			return rejected, err
+		}
+
+		return card.confirmAction(ctx, action, token)
+	})()
+
+	if result != nil {
+		span.SetAttributes(attribute.Stringer("gohever.manage.outcome", result.Outcome))
+	}
+
+	return result, err
+}
+
+// Blocks the card, e.g. when it's lost or stolen. Like loads, actions the site refuses result in
+// OutcomeRejected rather than an error.
+func (card *Card) Block() (*ManageResult, error) {
+	return card.BlockContext(context.Background())
+}
+
+func (card *Card) BlockContext(ctx context.Context) (*ManageResult, error) {
+	return card.manage(ctx, ManageBlock)
+}
+
+// Unblocks a blocked card
+func (card *Card) Unblock() (*ManageResult, error) {
+	return card.UnblockContext(context.Background())
+}
+
+func (card *Card) UnblockContext(ctx context.Context) (*ManageResult, error) {
+	return card.manage(ctx, ManageUnblock)
+}
+
+// Orders a replacement of a lost or damaged card. The card is blocked by the site once the
+// replacement is ordered, so this can't be undone.
+func (card *Card) RequestReplacement() (*ManageResult, error) {
+	return card.RequestReplacementContext(context.Background())
+}
+
+func (card *Card) RequestReplacementContext(ctx context.Context) (*ManageResult, error) {
+	return card.manage(ctx, ManageReplace)
+}
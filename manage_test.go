@@ -0,0 +1,176 @@
+package gohever
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+func manageRequestMock(action, body string) *testutils.MockedRequest {
+	return testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+		Status(200).
+		Body(body).
+		MatchFormData(testutils.FormData{"om": action, "confirm": "0"})
+}
+
+func manageConfirmMock(action, body string) *testutils.MockedRequest {
+	return testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").
+		Status(200).
+		Body(body).
+		MatchFormData(testutils.FormData{"om": action, "confirm": "1", "confirm_token": "a1b2c3d4"})
+}
+
+// Mocks both steps of a management action: asking for it and confirming it with the token
+func manageMocks(action, confirmation, result string) []*testutils.MockedRequest {
+	return []*testutils.MockedRequest{
+		manageRequestMock(action, confirmation).Once(),
+		manageConfirmMock(action, result).Once(),
+	}
+}
+
+func TestCardManage(t *testing.T) {
+	tests := []struct {
+		name   string
+		action ManageAction
+		manage func(card *Card) (*ManageResult, error)
+	}{
+		{"block", ManageBlock, (*Card).Block},
+		{"unblock", ManageUnblock, (*Card).Unblock},
+		{"replace", ManageReplace, (*Card).RequestReplacement},
+	}
+
+	for _, test := range tests {
+		t.Run("should "+test.name+" the card using the confirmation token", func(t *testing.T) {
+			client := SetupTestClient(t, TestClientConfig{
+				Authenticated:    true,
+				EnableManagement: true,
+				Mocks:            manageMocks(test.name, fixtureManageConfirm, fixtureManageDone),
+			})
+
+			result, err := test.manage(newCard(client, TypeKeva))
+
+			assert.NoError(t, err)
+			assert.Equal(t, &ManageResult{
+				Action:          test.action,
+				Outcome:         OutcomeDone,
+				ReferenceNumber: "55667788",
+				RawMessage:      "הבקשה התקבלה. מספר פנייה: 55667788",
+			}, result)
+		})
+	}
+
+	t.Run("should report actions rejected by the site", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks:            manageMocks("block", fixtureManageConfirm, fixtureManageRejected),
+		})
+
+		result, err := newCard(client, TypeKeva).Block()
+
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeRejected, result.Outcome)
+		assert.Equal(t, "הכרטיס כבר חסום", result.RawMessage)
+	})
+
+	t.Run("should not confirm actions rejected up front", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks: []*testutils.MockedRequest{
+				manageRequestMock("block", fixtureManageRejected).Once(),
+				manageConfirmMock("block", fixtureManageDone).ExpectNot(),
+			},
+		})
+
+		result, err := newCard(client, TypeKeva).Block()
+
+		assert.NoError(t, err)
+		assert.Equal(t, &ManageResult{
+			Action:     ManageBlock,
+			Outcome:    OutcomeRejected,
+			RawMessage: "הכרטיס כבר חסום",
+		}, result)
+	})
+
+	t.Run("should fail without a confirmation token", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks: []*testutils.MockedRequest{
+				manageRequestMock("unblock", "<html></html>").Once(),
+				manageConfirmMock("unblock", fixtureManageDone).ExpectNot(),
+			},
+		})
+
+		_, err := newCard(client, TypeKeva).Unblock()
+		assert.ErrorIs(t, err, ErrUnableToParseConfirmation)
+	})
+
+	t.Run("should fail when the outcome isn't recognized", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks:            manageMocks("block", fixtureManageConfirm, "<html></html>"),
+		})
+
+		result, err := newCard(client, TypeKeva).Block()
+
+		assert.ErrorIs(t, err, ErrUnableToParseConfirmation)
+		assert.Nil(t, result)
+	})
+
+	t.Run("should not manage the card unless enabled", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").ExpectNot(),
+			},
+		})
+
+		card := newCard(client, TypeKeva)
+
+		for _, manage := range []func() (*ManageResult, error){card.Block, card.Unblock, card.RequestReplacement} {
+			result, err := manage()
+
+			assert.ErrorIs(t, err, ErrManagementNotEnabled)
+			assert.Nil(t, result)
+		}
+	})
+
+	t.Run("should use the query params of the product", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated:    true,
+			EnableManagement: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx?food=1").
+					Once().
+					Status(200).
+					Body(fixtureManageConfirm).
+					MatchFormData(testutils.FormData{"om": "block", "confirm": "0"}),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx?food=1").
+					Once().
+					Status(200).
+					Body(fixtureManageDone).
+					MatchFormData(testutils.FormData{"om": "block", "confirm": "1", "confirm_token": "a1b2c3d4"}),
+			},
+		})
+
+		result, err := newCard(client, TypeTeamim).Block()
+
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeDone, result.Outcome)
+	})
+}
+
+func TestManageResultEncoding(t *testing.T) {
+	data, err := json.Marshal(ManageResult{Action: ManageReplace, Outcome: OutcomeDone, ReferenceNumber: "1"})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"action": "replace", "outcome": "done", "reference_number": "1", "raw_message": ""}`, string(data))
+
+	var result ManageResult
+	assert.Error(t, json.Unmarshal([]byte(`{"outcome": "maybe"}`), &result))
+}
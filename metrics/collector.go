@@ -0,0 +1,187 @@
+// Package metrics exposes the status of HEVER cards as Prometheus metrics.
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yardnsm/gohever"
+)
+
+const namespace = "gohever"
+
+// Collect has no context of its own, so a hanging site won't hang the scrapes for longer than this
+const collectTimeout = 30 * time.Second
+
+var labels = []string{"account", "flavor", "card_type"}
+
+var (
+	descCurrentBalance = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "card", "current_balance"),
+		"The current load on the card.",
+		labels, nil,
+	)
+	descRemainingMonthlyAmount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "card", "remaining_monthly_amount"),
+		"The remaining load until the end of the month.",
+		labels, nil,
+	)
+	descRemainingOnCardAmount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "card", "remaining_on_card_amount"),
+		"The remaining load until the card will be full.",
+		labels, nil,
+	)
+	descMonthlyUsage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "card", "monthly_usage_ratio"),
+		"The ratio of the monthly quota that was used.",
+		labels, nil,
+	)
+	descLeftovers = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "card", "leftovers"),
+		"The balance left from the previous month.",
+		labels, nil,
+	)
+	descScrapeSuccess = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "success"),
+		"Whether the last attempt to get the card status was successful.",
+		labels, nil,
+	)
+	descScrapeErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "errors_total"),
+		"The number of failed attempts to get the card status.",
+		labels, nil,
+	)
+)
+
+// A card to collect metrics from
+type Target struct {
+	Account string
+	Flavor  string
+	Card    gohever.CardInterface
+}
+
+// Returns the targets for all of the cards of a client
+func ClientTargets(account string, client *gohever.Client) []Target {
+	var targets []Target
+
+	for _, card := range client.Cards() {
+		targets = append(targets, Target{
+			Account: account,
+			Flavor:  client.Flavor().String(),
+			Card:    card,
+		})
+	}
+
+	return targets
+}
+
+type target struct {
+	Target
+
+	mu          sync.Mutex
+	status      *gohever.CardStatus
+	success     bool
+	errors      int
+	attemptedAt time.Time
+}
+
+func (t *target) labelValues() []string {
+	return []string{t.Account, t.Flavor, t.Card.Type().String()}
+}
+
+// Collector is a prometheus.Collector getting the status of the cards on demand. Statuses are
+// cached for a given TTL, so frequent scrapes won't hammer the site. The cards are collected
+// concurrently, and cards sharing a client share its session, logging in once for all of them.
+type Collector struct {
+	ttl     time.Duration
+	targets []*target
+
+	now func() time.Time
+}
+
+func NewCollector(ttl time.Duration, targets ...Target) *Collector {
+	collector := &Collector{
+		ttl: ttl,
+		now: time.Now,
+	}
+
+	for _, t := range targets {
+		collector.targets = append(collector.targets, &target{Target: t})
+	}
+
+	return collector
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descCurrentBalance
+	ch <- descRemainingMonthlyAmount
+	ch <- descRemainingOnCardAmount
+	ch <- descMonthlyUsage
+	ch <- descLeftovers
+	ch <- descScrapeSuccess
+	ch <- descScrapeErrors
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+
+	for _, t := range c.targets {
+		wg.Add(1)
+
+		go func(t *target) {
+			defer wg.Done()
+			c.collectTarget(t, ch)
+		}(t)
+	}
+
+	wg.Wait()
+}
+
+func (c *Collector) collectTarget(t *target, ch chan<- prometheus.Metric) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Failed attempts are cached as well, so a broken site won't be retried on every scrape
+	if t.attemptedAt.IsZero() || c.now().Sub(t.attemptedAt) >= c.ttl {
+		t.attemptedAt = c.now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		status, err := t.Card.GetStatusContext(ctx)
+		cancel()
+
+		if err != nil {
+			t.success = false
+			t.errors++
+		} else {
+			t.success = true
+			t.status = status
+		}
+	}
+
+	labelValues := t.labelValues()
+
+	success := 0.0
+	if t.success {
+		success = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(descScrapeSuccess, prometheus.GaugeValue, success, labelValues...)
+	ch <- prometheus.MustNewConstMetric(descScrapeErrors, prometheus.CounterValue, float64(t.errors), labelValues...)
+
+	// Keep reporting the last known status, even if the last attempt failed
+	if t.status == nil {
+		return
+	}
+
+	for desc, value := range map[*prometheus.Desc]float64{
+		descCurrentBalance:         t.status.CurrentBalance,
+		descRemainingMonthlyAmount: t.status.RemainingMonthlyAmount,
+		descRemainingOnCardAmount:  t.status.RemainingOnCardAmount,
+		descMonthlyUsage:           t.status.MonthlyUsage,
+		descLeftovers:              t.status.Leftovers,
+	} {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+	}
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+// A card returning a preset status, counting the calls made to it
+type stubCard struct {
+	gohever.CardInterface
+
+	cardType gohever.CardType
+	status   *gohever.CardStatus
+	err      error
+	calls    int
+}
+
+func (c *stubCard) Type() gohever.CardType {
+	return c.cardType
+}
+
+func (c *stubCard) GetStatusContext(ctx context.Context) (*gohever.CardStatus, error) {
+	c.calls++
+	return c.status, c.err
+}
+
+func setupStatus(balance float64) *gohever.CardStatus {
+	return &gohever.CardStatus{
+		MaxMonthlyAmount:       3000,
+		MaxOnCardAmount:        1000,
+		CurrentBalance:         balance,
+		RemainingMonthlyAmount: 2500,
+		RemainingOnCardAmount:  1000 - balance,
+		MonthlyUsage:           0.25,
+		Leftovers:              50,
+	}
+}
+
+func TestCollector(t *testing.T) {
+	t.Run("should expose the card status", func(t *testing.T) {
+		keva := &stubCard{cardType: gohever.TypeKeva, status: setupStatus(400)}
+		teamim := &stubCard{cardType: gohever.TypeTeamim, status: setupStatus(100)}
+
+		collector := NewCollector(time.Minute,
+			Target{Account: "me", Flavor: "hvr", Card: keva},
+			Target{Account: "me", Flavor: "hvr", Card: teamim},
+		)
+
+		expected := `
+# HELP gohever_card_current_balance The current load on the card.
+# TYPE gohever_card_current_balance gauge
+gohever_card_current_balance{account="me",card_type="keva",flavor="hvr"} 400
+gohever_card_current_balance{account="me",card_type="teamim",flavor="hvr"} 100
+# HELP gohever_card_leftovers The balance left from the previous month.
+# TYPE gohever_card_leftovers gauge
+gohever_card_leftovers{account="me",card_type="keva",flavor="hvr"} 50
+gohever_card_leftovers{account="me",card_type="teamim",flavor="hvr"} 50
+# HELP gohever_card_monthly_usage_ratio The ratio of the monthly quota that was used.
+# TYPE gohever_card_monthly_usage_ratio gauge
+gohever_card_monthly_usage_ratio{account="me",card_type="keva",flavor="hvr"} 0.25
+gohever_card_monthly_usage_ratio{account="me",card_type="teamim",flavor="hvr"} 0.25
+# HELP gohever_card_remaining_monthly_amount The remaining load until the end of the month.
+# TYPE gohever_card_remaining_monthly_amount gauge
+gohever_card_remaining_monthly_amount{account="me",card_type="keva",flavor="hvr"} 2500
+gohever_card_remaining_monthly_amount{account="me",card_type="teamim",flavor="hvr"} 2500
+# HELP gohever_card_remaining_on_card_amount The remaining load until the card will be full.
+# TYPE gohever_card_remaining_on_card_amount gauge
+gohever_card_remaining_on_card_amount{account="me",card_type="keva",flavor="hvr"} 600
+gohever_card_remaining_on_card_amount{account="me",card_type="teamim",flavor="hvr"} 900
+# HELP gohever_scrape_errors_total The number of failed attempts to get the card status.
+# TYPE gohever_scrape_errors_total counter
+gohever_scrape_errors_total{account="me",card_type="keva",flavor="hvr"} 0
+gohever_scrape_errors_total{account="me",card_type="teamim",flavor="hvr"} 0
+# HELP gohever_scrape_success Whether the last attempt to get the card status was successful.
+# TYPE gohever_scrape_success gauge
+gohever_scrape_success{account="me",card_type="keva",flavor="hvr"} 1
+gohever_scrape_success{account="me",card_type="teamim",flavor="hvr"} 1
+`
+
+		assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+	})
+
+	t.Run("should cache the status for the ttl", func(t *testing.T) {
+		keva := &stubCard{cardType: gohever.TypeKeva, status: setupStatus(400)}
+		collector := NewCollector(time.Minute, Target{Account: "me", Flavor: "hvr", Card: keva})
+
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		collector.now = func() time.Time { return now }
+
+		testutil.CollectAndCount(collector)
+		testutil.CollectAndCount(collector)
+		assert.Equal(t, 1, keva.calls)
+
+		now = now.Add(time.Minute)
+
+		testutil.CollectAndCount(collector)
+		assert.Equal(t, 2, keva.calls)
+	})
+
+	t.Run("should export scrape errors and keep the last known status", func(t *testing.T) {
+		keva := &stubCard{cardType: gohever.TypeKeva, status: setupStatus(400)}
+		collector := NewCollector(0, Target{Account: "me", Flavor: "hvr", Card: keva})
+
+		testutil.CollectAndCount(collector)
+
+		keva.status = nil
+		keva.err = errors.New("oops")
+
+		expected := `
+# HELP gohever_card_current_balance The current load on the card.
+# TYPE gohever_card_current_balance gauge
+gohever_card_current_balance{account="me",card_type="keva",flavor="hvr"} 400
+# HELP gohever_scrape_errors_total The number of failed attempts to get the card status.
+# TYPE gohever_scrape_errors_total counter
+gohever_scrape_errors_total{account="me",card_type="keva",flavor="hvr"} 1
+# HELP gohever_scrape_success Whether the last attempt to get the card status was successful.
+# TYPE gohever_scrape_success gauge
+gohever_scrape_success{account="me",card_type="keva",flavor="hvr"} 0
+`
+
+		assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+			"gohever_card_current_balance",
+			"gohever_scrape_errors_total",
+			"gohever_scrape_success",
+		))
+	})
+}
+
+func TestCollectorSharedClient(t *testing.T) {
+	fake := testutils.NewFakeHever(testutils.FakeHeverConfig{
+		Username: "TestUsername",
+		Password: "TestPassword",
+	}).SetupTest(t)
+
+	client := gohever.NewClient(gohever.FlavorHvr(), gohever.Config{
+		Credentials: gohever.BasicCredentials("TestUsername", "TestPassword"),
+		BaseURL:     fake.URL(),
+	})
+
+	keva, _ := client.Card(gohever.TypeKeva)
+	teamim, _ := client.Card(gohever.TypeTeamim)
+
+	// The cards are collected concurrently, over a single login
+	collector := NewCollector(time.Minute,
+		Target{Account: "me", Flavor: "hvr", Card: keva},
+		Target{Account: "me", Flavor: "hvr", Card: teamim},
+	)
+
+	expected := `
+# HELP gohever_scrape_success Whether the last attempt to get the card status was successful.
+# TYPE gohever_scrape_success gauge
+gohever_scrape_success{account="me",card_type="keva",flavor="hvr"} 1
+gohever_scrape_success{account="me",card_type="teamim",flavor="hvr"} 1
+`
+
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected), "gohever_scrape_success"))
+	assert.Equal(t, 1, fake.Logins())
+}
+
+func TestClientTargets(t *testing.T) {
+	client := gohever.NewClient(gohever.FlavorHvr(), gohever.Config{})
+	targets := ClientTargets("me", client)
+
+	assert.Len(t, targets, 2)
+	assert.Equal(t, "hvr", targets[0].Flavor)
+	assert.Equal(t, gohever.TypeKeva, targets[0].Card.Type())
+	assert.Equal(t, gohever.TypeTeamim, targets[1].Card.Type())
+}
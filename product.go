@@ -0,0 +1,76 @@
+package gohever
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Describes a card product sold on the site. All of the products are served from an orders page
+// sharing the same structure, and differ only in where the page is and how its variables are named.
+type CardProduct struct {
+	Type CardType
+
+	// The orders page serving the card config, balance, history and loads
+	Path string
+
+	// Query params sent with every request of the card
+	QueryParams map[string]string
+
+	// The prefix of the factor variables within the page scripts. For example, "gift_card_factor"
+	// matches both "gift_card_factor1" and "gift_card_factor1_price".
+	FactorVariable string
+}
+
+const defaultFactorVariable = "gift_card_factor"
+
+// The products known to this package
+var (
+	ProductKeva = CardProduct{
+		Type: TypeKeva,
+		Path: urlGiftCard,
+	}
+
+	ProductTeamim = CardProduct{
+		Type:        TypeTeamim,
+		Path:        urlGiftCard,
+		QueryParams: map[string]string{queryParamFoodCard: "1"},
+	}
+
+	ProductSheli = CardProduct{
+		Type: TypeSheli,
+		Path: urlGiftCard,
+	}
+)
+
+// Returns the product known to this package for the given card type
+func productOf(cardType CardType) (CardProduct, error) {
+	for _, product := range []CardProduct{ProductKeva, ProductTeamim, ProductSheli} {
+		if product.Type == cardType {
+			return product, nil
+		}
+	}
+
+	return CardProduct{}, fmt.Errorf("%w: %s", ErrCardNotSupported, cardType)
+}
+
+// The regexes for reading the factors of a product out of its config page
+type factorRegexes struct {
+	factors [3]*regexp.Regexp
+	prices  [3]*regexp.Regexp
+}
+
+func newFactorRegexes(variable string) *factorRegexes {
+	if variable == "" {
+		variable = defaultFactorVariable
+	}
+
+	var regexes factorRegexes
+	for i := range regexes.factors {
+		name := regexp.QuoteMeta(fmt.Sprintf("%s%d", variable, i+1))
+
+		regexes.factors[i] = regexp.MustCompile("var " + name + " = ([0-9\\.]+);")
+		regexes.prices[i] = regexp.MustCompile("var " + name + "_price = ([0-9\\.]+);")
+	}
+
+	return &regexes
+}
@@ -0,0 +1,105 @@
+package gohever
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const redactedValue = "[REDACTED]"
+
+var (
+	// Form fields that hold secrets, either as url-encoded bodies or as HTML inputs
+	regexSensitiveFormField = regexp.MustCompile(
+		"(^|\\W)(tz|password|card_num|card_month|card_year)=([^&\\s\"']*)")
+	regexSensitiveInput = regexp.MustCompile(
+		"(?i)<input[^>]*\\bname=[\"']?(?:tz|password|card_num|card_month|card_year)[\"']?[^>]*>")
+	regexInputValue = regexp.MustCompile("(?i)(\\bvalue=)(\"[^\"]*\"|'[^']*'|[^\\s>]*)")
+
+	// Anything that looks like a credit card number
+	regexCardNumber = regexp.MustCompile("\\b\\d{13,19}\\b")
+
+	// Headers that carry the session
+	sensitiveHeaders = []string{"Cookie", "Set-Cookie", "Authorization"}
+)
+
+// Masks all but the last 4 digits of a card number
+func MaskCardNumber(number string) string {
+	if len(number) <= 4 {
+		return strings.Repeat("*", len(number))
+	}
+
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+// Scrubs credentials and credit card details from url-encoded form data, HTML and free text
+func redact(s string) string {
+	s = regexSensitiveFormField.ReplaceAllStringFunc(s, func(match string) string {
+		parts := regexSensitiveFormField.FindStringSubmatch(match)
+		value := redactedValue
+
+		if parts[2] == "card_num" {
+			value = MaskCardNumber(parts[3])
+		}
+
+		return parts[1] + parts[2] + "=" + value
+	})
+
+	s = regexSensitiveInput.ReplaceAllStringFunc(s, func(match string) string {
+		return regexInputValue.ReplaceAllString(match, "${1}\""+redactedValue+"\"")
+	})
+
+	return regexCardNumber.ReplaceAllStringFunc(s, MaskCardNumber)
+}
+
+func redactHeaders(header http.Header) {
+	for _, key := range sensitiveHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, redactedValue)
+		}
+	}
+}
+
+func redactRequestLog(rl *resty.RequestLog) error {
+	rl.Body = redact(rl.Body)
+	redactHeaders(rl.Header)
+
+	return nil
+}
+
+func redactResponseLog(rl *resty.ResponseLog) error {
+	rl.Body = redact(rl.Body)
+	redactHeaders(rl.Header)
+
+	return nil
+}
+
+// An error whose message was scrubbed from secrets, while still allowing errors.Is and errors.As
+// to reach the original error
+type redactedError struct {
+	err error
+}
+
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var alreadyRedacted *redactedError
+	if errors.As(err, &alreadyRedacted) {
+		return err
+	}
+
+	return &redactedError{err}
+}
+
+func (e *redactedError) Error() string {
+	return redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
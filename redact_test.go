@@ -0,0 +1,131 @@
+package gohever
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yardnsm/gohever/testutils"
+)
+
+// A resty.Logger capturing everything into a buffer
+type bufferLogger struct {
+	buffer bytes.Buffer
+}
+
+func (l *bufferLogger) Errorf(format string, v ...interface{}) { fmt.Fprintf(&l.buffer, format, v...) }
+func (l *bufferLogger) Warnf(format string, v ...interface{})  { fmt.Fprintf(&l.buffer, format, v...) }
+func (l *bufferLogger) Debugf(format string, v ...interface{}) { fmt.Fprintf(&l.buffer, format, v...) }
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "form data",
+			input:    "oMode=login&password=secret&tz=123456789",
+			expected: "oMode=login&password=[REDACTED]&tz=[REDACTED]",
+		},
+		{
+			name:     "credit card form data",
+			input:    "card_month=04&card_num=45801234567899012&card_year=2023&price=500",
+			expected: "card_month=[REDACTED]&card_num=*************9012&card_year=[REDACTED]&price=500",
+		},
+		{
+			name:     "html inputs",
+			input:    `<input type="text" name="tz" value="123456789"><input name="oMode" value="login">`,
+			expected: `<input type="text" name="tz" value="[REDACTED]"><input name="oMode" value="login">`,
+		},
+		{
+			name:     "card numbers in free text",
+			input:    "the card 4580123456789012 was declined",
+			expected: "the card ************9012 was declined",
+		},
+		{
+			name:     "nothing to redact",
+			input:    "balance_only=1&current_max_load=1000",
+			expected: "balance_only=1&current_max_load=1000",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, redact(test.input))
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := redactError(fmt.Errorf("unexpected response: %w", ErrUnableToParseCardConfig))
+	err = fmt.Errorf("wrapped: %w", err)
+
+	assert.ErrorIs(t, err, ErrUnableToParseCardConfig)
+	assert.Equal(t, "wrapped: unexpected response: failed to parse the card config", err.Error())
+
+	err = redactError(fmt.Errorf("parsing %q: invalid syntax", "password=secret"))
+	assert.Equal(t, "parsing \"password=[REDACTED]\": invalid syntax", err.Error())
+}
+
+func TestSecretsFormatting(t *testing.T) {
+	credentials := Credentials{Username: "123456789", Password: "secret"}
+	creditCard := CreditCard{Name: "personal", Number: "45801234567899012", Month: "04", Year: "2023"}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		t.Run(format, func(t *testing.T) {
+			out := fmt.Sprintf(format, credentials) + fmt.Sprintf(format, creditCard)
+
+			assert.NotContains(t, out, "123456789")
+			assert.NotContains(t, out, "secret")
+			assert.NotContains(t, out, "45801234567899012")
+			assert.NotContains(t, out, "2023")
+
+			assert.Contains(t, out, "personal")
+			assert.Contains(t, out, "9012")
+		})
+	}
+
+	t.Run("nested in other values", func(t *testing.T) {
+		out := fmt.Sprintf("%+v", struct{ Cards []CreditCard }{[]CreditCard{creditCard}})
+
+		assert.NotContains(t, out, "45801234567899012")
+	})
+}
+
+func TestDebugLogRedaction(t *testing.T) {
+	client := SetupTestClient(t, TestClientConfig{
+		Mocks: []*testutils.MockedRequest{
+			testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthConfig),
+			testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Status(200),
+			testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthUnsuccessful),
+			testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body(fixtureLoadDeclined),
+		},
+	})
+
+	logger := &bufferLogger{}
+	client.r.SetDebug(true).SetLogger(logger)
+
+	err := client.Auth.Authenticate()
+	assert.ErrorIs(t, err, ErrAuthenticatedFailed)
+
+	client.isAuthenticated = true
+	_, err = newCard(client, TypeKeva).Load(setupCardStatus(400, 0, 0), 500)
+	assert.NoError(t, err)
+
+	out := logger.buffer.String()
+
+	// Make sure we've actually captured the logs
+	assert.Contains(t, out, "~~~ REQUEST ~~~")
+	assert.Contains(t, out, "oMode=login")
+	assert.Contains(t, out, "price=500")
+
+	assert.NotContains(t, out, "TestUsername")
+	assert.NotContains(t, out, "TestPassword")
+	assert.NotContains(t, out, "45801234567899012")
+	assert.NotContains(t, out, "card_year=2023")
+	assert.NotContains(t, out, "card_month=04")
+
+	assert.Contains(t, out, "card_num=*************9012")
+}
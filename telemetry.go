@@ -0,0 +1,99 @@
+package gohever
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yardnsm/gohever"
+
+// The tracer and instruments reporting the client's operations
+type telemetry struct {
+	tracer trace.Tracer
+
+	logins            metric.Int64Counter
+	reauthentications metric.Int64Counter
+	loads             metric.Int64Counter
+	endpointDuration  metric.Float64Histogram
+}
+
+// Creates the client telemetry. When no providers are given, the global ones are used, which are
+// no-ops unless set by the application.
+func newTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) *telemetry {
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	t := &telemetry{
+		tracer: tracerProvider.Tracer(instrumentationName),
+	}
+
+	var err error
+
+	// Instruments are still usable when failing to create them, so errors are only reported
+	handleErr := func(err error) {
+		if err != nil {
+			otel.Handle(err)
+		}
+	}
+
+	t.logins, err = meter.Int64Counter("gohever.auth.logins",
+		metric.WithDescription("Logins to the site, by outcome"))
+	handleErr(err)
+
+	t.reauthentications, err = meter.Int64Counter("gohever.auth.reauthentications",
+		metric.WithDescription("Logins triggered by an expired session"))
+	handleErr(err)
+
+	t.loads, err = meter.Int64Counter("gohever.card.loads",
+		metric.WithDescription("Card loads, by card type and load status"))
+	handleErr(err)
+
+	t.endpointDuration, err = meter.Float64Histogram("gohever.endpoint.duration",
+		metric.WithDescription("The duration of calls to the site's endpoints"),
+		metric.WithUnit("s"))
+	handleErr(err)
+
+	return t
+}
+
+func (t *telemetry) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(redactError(err))
+		span.SetStatus(codes.Error, redact(err.Error()))
+	}
+
+	span.End()
+}
+
+func (t *telemetry) recordLogin(ctx context.Context, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	t.logins.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+func (t *telemetry) recordEndpointCall(ctx context.Context, endpoint, method string, statusCode int, duration time.Duration) {
+	t.endpointDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("endpoint", endpoint),
+		attribute.String("http.request.method", method),
+		attribute.Int("http.response.status_code", statusCode),
+	))
+}
@@ -0,0 +1,244 @@
+package gohever
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yardnsm/gohever/testutils"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testTelemetry struct {
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+// Sets up in-memory exporters for the client's telemetry
+func setupTestTelemetry(client *Client) *testTelemetry {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	client.telemetry = newTelemetry(
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	)
+
+	return &testTelemetry{spans, reader}
+}
+
+// Returns the names of the ended spans which are children of the given span
+func (tt *testTelemetry) children(parent sdktrace.ReadOnlySpan) []string {
+	var names []string
+	for _, span := range tt.spans.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			names = append(names, span.Name())
+		}
+	}
+
+	return names
+}
+
+func (tt *testTelemetry) span(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	for _, span := range tt.spans.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	require.Failf(t, "span not found", "no span named %q", name)
+	return nil
+}
+
+// Returns the sum of a counter, for the data points matching the given attributes
+func (tt *testTelemetry) counter(t *testing.T, name string, attrs ...attribute.KeyValue) int64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, tt.reader.Collect(context.Background(), &rm))
+
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				matches := true
+				for _, attr := range attrs {
+					value, ok := dp.Attributes.Value(attr.Key)
+					matches = matches && ok && value == attr.Value
+				}
+
+				if matches {
+					total += dp.Value
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+// Returns the endpoints recorded in the endpoint duration histogram
+func (tt *testTelemetry) endpoints(t *testing.T) map[string]uint64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, tt.reader.Collect(context.Background(), &rm))
+
+	endpoints := make(map[string]uint64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "gohever.endpoint.duration" {
+				continue
+			}
+
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				endpoint, _ := dp.Attributes.Value("endpoint")
+				endpoints[endpoint.AsString()] += dp.Count
+			}
+		}
+	}
+
+	return endpoints
+}
+
+func TestTelemetry(t *testing.T) {
+	t.Run("should trace getting the card status", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		tt := setupTestTelemetry(client)
+
+		_, err := newCard(client, TypeKeva).GetStatus()
+		require.NoError(t, err)
+
+		span := tt.span(t, "Card.GetStatus")
+		assert.False(t, span.Parent().IsValid())
+		assert.Equal(t, []string{"GET card_config", "POST card_balance"}, tt.children(span))
+
+		assert.Equal(t, map[string]uint64{"card_config": 1, "card_balance": 1}, tt.endpoints(t))
+	})
+
+	t.Run("should trace under the caller's span", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		tt := setupTestTelemetry(client)
+
+		ctx, parent := client.telemetry.tracer.Start(context.Background(), "caller")
+		_, err := newCard(client, TypeKeva).GetStatusContext(ctx)
+		parent.End()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Card.GetStatus"}, tt.children(tt.span(t, "caller")))
+	})
+
+	t.Run("should trace the authentication as part of the operation", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthSuccessful),
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		tt := setupTestTelemetry(client)
+
+		_, err := newCard(client, TypeKeva).GetStatus()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Auth.Authenticate", "GET card_config", "POST card_balance"},
+			tt.children(tt.span(t, "Card.GetStatus")))
+		assert.Equal(t, []string{"GET auth_config", "GET auth_pixel", "POST authenticate"},
+			tt.children(tt.span(t, "Auth.Authenticate")))
+
+		assert.EqualValues(t, 1, tt.counter(t, "gohever.auth.logins", attribute.String("outcome", "success")))
+		assert.EqualValues(t, 0, tt.counter(t, "gohever.auth.reauthentications"))
+	})
+
+	t.Run("should count failed logins", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthUnsuccessful),
+			},
+		})
+
+		tt := setupTestTelemetry(client)
+
+		assert.ErrorIs(t, client.Auth.Authenticate(), ErrAuthenticatedFailed)
+
+		span := tt.span(t, "Auth.Authenticate")
+		assert.Equal(t, "Error", span.Status().Code.String())
+
+		assert.EqualValues(t, 1, tt.counter(t, "gohever.auth.logins", attribute.String("outcome", "failure")))
+	})
+
+	t.Run("should count re-authentications", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(302).Header("Location", "/logout.aspx"),
+				testutils.NewMockedRequest("GET", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthConfig),
+				testutils.NewMockedRequest("GET", "/acmplt.asmx/logo?t=1234123412341").Status(200),
+				testutils.NewMockedRequest("POST", "/signin.aspx?bs=1").Status(200).Body(fixtureAuthSuccessful),
+				testutils.NewMockedRequest("GET", "/orders/gift_2000.aspx").Once().Status(200).Body(fixtureCardConfig),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx").Status(200).Body(fixtureCardBalance),
+			},
+		})
+
+		tt := setupTestTelemetry(client)
+
+		_, err := newCard(client, TypeKeva).GetStatus()
+		require.NoError(t, err)
+
+		assert.EqualValues(t, 1, tt.counter(t, "gohever.auth.reauthentications"))
+		assert.EqualValues(t, 1, tt.counter(t, "gohever.auth.logins"))
+		assert.Equal(t, uint64(2), tt.endpoints(t)["card_config"])
+	})
+
+	t.Run("should count loads by status", func(t *testing.T) {
+		client := SetupTestClient(t, TestClientConfig{
+			Authenticated: true,
+			Mocks: []*testutils.MockedRequest{
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx?food=1").Once().Status(200).Body(fixtureLoadDeclined),
+				testutils.NewMockedRequest("POST", "/orders/gift_2000.aspx?food=1").Once().Status(200).Body(fixtureLoadSuccess),
+			},
+		})
+
+		client.config.CreditCards = BasicCreditCards(
+			CreditCard{Name: "personal", Number: "4580111122223333", Month: "01", Year: "2030"},
+			CreditCard{Name: "work", Number: "4580444455556666", Month: "02", Year: "2031"},
+		)
+
+		tt := setupTestTelemetry(client)
+
+		_, err := newCard(client, TypeTeamim).Load(setupCardStatus(400, 0, 0), 500, WithCreditCardFallback())
+		require.NoError(t, err)
+
+		span := tt.span(t, "Card.Load")
+		assert.Equal(t, []string{"POST load_card", "POST load_card"}, tt.children(span))
+		assert.Contains(t, span.Attributes(), attribute.String("gohever.load.status", "success"))
+
+		teamim := attribute.String("gohever.card.type", "teamim")
+		assert.EqualValues(t, 1, tt.counter(t, "gohever.card.loads", teamim, attribute.String("gohever.load.status", "error")))
+		assert.EqualValues(t, 1, tt.counter(t, "gohever.card.loads", teamim, attribute.String("gohever.load.status", "success")))
+	})
+}
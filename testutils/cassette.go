@@ -0,0 +1,317 @@
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// The value scrubbed secrets are replaced with
+const Scrubbed = "SCRUBBED"
+
+// Serial numbers are replaced with a fixed one, so requests sending them back still match
+const scrubbedSerialNumber = "00000000-0000-0000-0000-000000000000"
+
+var (
+	regexCardNumber   = regexp.MustCompile(`\b\d{13,19}\b`)
+	regexSerialNumber = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+	// The greeting of the logged-in user, e.g. "שלום ישראל ישראלי". Words merely ending with it, like
+	// "תשלום", are left alone.
+	regexGreeting = regexp.MustCompile(`(^|[^\p{Hebrew}])(שלום,?[ \t]+)[^<\r\n]+`)
+)
+
+// The form fields scrubbed by default
+var DefaultScrubbedFields = []string{"tz", "password", "card_num", "card_month", "card_year"}
+
+// The ids of the elements which text is scrubbed by default: the holder name of the card page
+var DefaultScrubbedElements = []string{"card_holder"}
+
+// A recorded HTTP session, which can be replayed using MockServer.Replay
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// A single request made during a session, and the response it got
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method string `json:"method"`
+
+	// The request URI, including the query
+	Path string `json:"path"`
+
+	Form FormData `json:"form,omitempty"`
+}
+
+type CassetteResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Loads a cassette saved with Cassette.Save
+func LoadCassette(fileName string) (*Cassette, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, err
+	}
+
+	return &cassette, nil
+}
+
+// Saves the cassette to a file
+func (c *Cassette) Save(fileName string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, append(data, '\n'), 0o644)
+}
+
+// Scrubs secrets out of recorded sessions
+type Scrubber struct {
+	// Form fields which values are scrubbed, both in requests and in the inputs of the recorded
+	// pages. DefaultScrubbedFields are used when empty.
+	Fields []string
+
+	// Elements which text is scrubbed, by their ids. DefaultScrubbedElements are used when empty.
+	Elements []string
+
+	// Strings scrubbed wherever they show up, e.g. the account holder's ID number
+	Secrets []string
+}
+
+func (s Scrubber) fields() []string {
+	if len(s.Fields) == 0 {
+		return DefaultScrubbedFields
+	}
+
+	return s.Fields
+}
+
+func (s Scrubber) elements() []string {
+	if len(s.Elements) == 0 {
+		return DefaultScrubbedElements
+	}
+
+	return s.Elements
+}
+
+func (s Scrubber) isField(key string) bool {
+	for _, field := range s.fields() {
+		if field == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scrubs secrets, names, card numbers and serial numbers out of the given text. The holder name and
+// the greeting of the logged-in user are always scrubbed.
+func (s Scrubber) Scrub(text string) string {
+	for _, secret := range s.Secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, Scrubbed)
+		}
+	}
+
+	for _, field := range s.fields() {
+		name := regexp.QuoteMeta(field)
+
+		text = regexp.MustCompile(`(name="`+name+`"[^>]*?value=")[^"]*`).ReplaceAllString(text, "${1}"+Scrubbed)
+		text = regexp.MustCompile(`((?:^|[?&])`+name+`=)[^&\s"']*`).ReplaceAllString(text, "${1}"+Scrubbed)
+	}
+
+	for _, id := range s.elements() {
+		name := regexp.QuoteMeta(id)
+		text = regexp.MustCompile(`(<[a-zA-Z][^>]*\sid="`+name+`"[^>]*>)[^<]*`).ReplaceAllString(text, "${1}"+Scrubbed)
+	}
+
+	text = regexGreeting.ReplaceAllString(text, "${1}${2}"+Scrubbed)
+
+	text = regexSerialNumber.ReplaceAllString(text, scrubbedSerialNumber)
+	text = regexCardNumber.ReplaceAllStringFunc(text, func(number string) string {
+		return strings.Repeat("0", len(number))
+	})
+
+	return text
+}
+
+func (s Scrubber) scrubForm(form url.Values) FormData {
+	if len(form) == 0 {
+		return nil
+	}
+
+	scrubbed := make(FormData, len(form))
+	for key, values := range form {
+		if s.isField(key) {
+			scrubbed[key] = Scrubbed
+		} else {
+			scrubbed[key] = s.Scrub(values[0])
+		}
+	}
+
+	return scrubbed
+}
+
+// Records the requests going through it into a cassette, scrubbing secrets on the way. Use it as
+// the transport of the HTTP client, e.g. using resty's SetTransport.
+type Recorder struct {
+	transport http.RoundTripper
+	scrubber  Scrubber
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// Creates a recorder sending the requests using the given transport, or http.DefaultTransport
+// when nil
+func NewRecorder(transport http.RoundTripper, scrubber Scrubber) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{
+		transport: transport,
+		scrubber:  scrubber,
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var form url.Values
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			form, _ = url.ParseQuery(string(body))
+		}
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Request: CassetteRequest{
+			Method: req.Method,
+			Path:   r.scrubber.Scrub(req.URL.RequestURI()),
+			Form:   r.scrubber.scrubForm(form),
+		},
+		Response: CassetteResponse{
+			Status: resp.StatusCode,
+			Body:   r.scrubber.Scrub(string(body)),
+		},
+	}
+
+	// Cookies are left out on purpose, they're not needed for replaying
+	for _, header := range []string{"Location", "Content-Type"} {
+		if value := resp.Header.Get(header); value != "" {
+			if interaction.Response.Headers == nil {
+				interaction.Response.Headers = make(map[string]string)
+			}
+
+			interaction.Response.Headers[header] = r.scrubber.Scrub(value)
+		}
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Returns the session recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Cassette{
+		Interactions: append([]Interaction(nil), r.cassette.Interactions...),
+	}
+}
+
+// Saves the session recorded so far to a file
+func (r *Recorder) Save(fileName string) error {
+	return r.Cassette().Save(fileName)
+}
+
+// Replays a recorded session. Every interaction is expected exactly once, matching on the method,
+// path and form data. Scrubbed form values match any value. Identical requests get their responses
+// in the recorded order, but the order of different requests is not enforced.
+func (m *MockServer) Replay(cassette *Cassette) *MockServer {
+	for _, interaction := range cassette.Interactions {
+		mocked := NewMockedRequest(interaction.Request.Method, interaction.Request.Path).
+			Once().
+			Status(interaction.Response.Status).
+			Body(interaction.Response.Body)
+
+		for key, value := range interaction.Response.Headers {
+			mocked.Header(key, value)
+		}
+
+		if interaction.Request.Form != nil {
+			mocked.bodyMatcher = matchScrubbedForm(interaction.Request.Form)
+		}
+
+		m.Mock(mocked)
+	}
+
+	return m
+}
+
+func matchScrubbedForm(form FormData) interface{} {
+	return mock.MatchedBy(func(body []byte) bool {
+		values, err := url.ParseQuery(string(body))
+		if err != nil || len(values) != len(form) {
+			return false
+		}
+
+		for key, expected := range form {
+			if !values.Has(key) {
+				return false
+			}
+
+			if expected != Scrubbed && values.Get(key) != expected {
+				return false
+			}
+		}
+
+		return true
+	})
+}
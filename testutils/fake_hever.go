@@ -0,0 +1,525 @@
+package testutils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The names of the cards served by FakeHever
+const (
+	FakeCardKeva   = "keva"
+	FakeCardTeamim = "teamim"
+)
+
+const (
+	fakeSessionCookie = "ASP.NET_SessionId"
+	fakeMinimumLoad   = 5
+)
+
+// A factor "step" of a fake card
+type FakeFactor struct {
+	Factor float64
+	Amount float64
+}
+
+// A row in the history of a fake card
+type FakeHistoryRow struct {
+	Id           string
+	Date         time.Time
+	Load         bool
+	BusinessName string
+	Amount       float64
+}
+
+// The state of a card served by FakeHever
+type FakeCard struct {
+	Factors []FakeFactor
+
+	MaxMonthlyAmount float64
+	MaxOnCardAmount  float64
+
+	// The current load on the card
+	Balance float64
+
+	// The amount loaded during the current month
+	MonthlyLoaded float64
+
+	SerialNumber string
+	History      []FakeHistoryRow
+
+	// Whether the card is blocked, in which case loads fail
+	Blocked bool
+
+	// The number of replacements ordered for the card
+	Replacements int
+}
+
+type FakeHeverConfig struct {
+	Username string
+	Password string
+
+	// The cards of the account, by their name (e.g. FakeCardKeva). Keva and Teamim cards with
+	// the common factors are used when nil.
+	Cards map[string]*FakeCard
+
+	// Credit card numbers which loads are declined
+	DeclinedCreditCards []string
+
+	// Used for dating the history rows, time.Now is used when nil
+	Now func() time.Time
+}
+
+// A stateful server simulating the HEVER site: logging in using the verify pixel, expiring
+// sessions, serving the cards config, balance and history, loading the cards, and blocking,
+// unblocking and replacing them using confirmation tokens.
+type FakeHever struct {
+	config FakeHeverConfig
+	server *httptest.Server
+
+	mu          sync.Mutex
+	sessions    map[string]*fakeSession
+	logins      int
+	loadNumbers int
+}
+
+type fakeSession struct {
+	pixelToken    string
+	pixelVerified bool
+	authenticated bool
+
+	// The management actions waiting for a confirmation, by their token
+	confirmations map[string]fakeConfirmation
+}
+
+type fakeConfirmation struct {
+	card   string
+	action string
+}
+
+// Creates a new fake server, which wont start until a test is attached
+func NewFakeHever(config FakeHeverConfig) *FakeHever {
+	if config.Cards == nil {
+		config.Cards = map[string]*FakeCard{
+			FakeCardKeva:   NewFakeCard("11111111-2222-3333-4444-555555555555"),
+			FakeCardTeamim: NewFakeCard("66666666-7777-8888-9999-000000000000"),
+		}
+	}
+
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	f := &FakeHever{
+		config:      config,
+		sessions:    make(map[string]*fakeSession),
+		loadNumbers: 10000000,
+	}
+
+	f.server = httptest.NewUnstartedServer(http.HandlerFunc(f.handle))
+
+	return f
+}
+
+// Creates a card with the common factors and limits of a HEVER card
+func NewFakeCard(serialNumber string) *FakeCard {
+	return &FakeCard{
+		Factors: []FakeFactor{
+			{Factor: 0.7, Amount: 1000},
+			{Factor: 0.75, Amount: 1000},
+			{Factor: 0.8, Amount: 1000},
+		},
+
+		MaxMonthlyAmount: 3000,
+		MaxOnCardAmount:  1000,
+		SerialNumber:     serialNumber,
+	}
+}
+
+// Setup the server for a test, will start the server and close is when the test ends
+func (f *FakeHever) SetupTest(t *testing.T) *FakeHever {
+	f.server.Start()
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+// Returns the URL of the fake server
+func (f *FakeHever) URL() string {
+	return f.server.URL
+}
+
+// Expires all of the sessions, as if the user has been idle for too long
+func (f *FakeHever) ExpireSessions() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sessions = make(map[string]*fakeSession)
+}
+
+// Returns the number of successful logins so far
+func (f *FakeHever) Logins() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.logins
+}
+
+// Returns a snapshot of the card with the given name
+func (f *FakeHever) Card(name string) (FakeCard, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	card, ok := f.config.Cards[name]
+	if !ok {
+		return FakeCard{}, false
+	}
+
+	snapshot := *card
+	snapshot.Factors = append([]FakeFactor(nil), card.Factors...)
+	snapshot.History = append([]FakeHistoryRow(nil), card.History...)
+
+	return snapshot, true
+}
+
+// Makes a purchase using the card, as if it was used in the given business
+func (f *FakeHever) Purchase(name, businessName string, amount float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	card, ok := f.config.Cards[name]
+	if !ok {
+		return fmt.Errorf("no such card: %s", name)
+	}
+
+	if amount > card.Balance {
+		return fmt.Errorf("not enough balance on card %s: %v", name, card.Balance)
+	}
+
+	card.Balance -= amount
+	f.appendHistory(card, false, businessName, amount)
+
+	return nil
+}
+
+func (f *FakeHever) appendHistory(card *FakeCard, load bool, businessName string, amount float64) {
+	card.History = append(card.History, FakeHistoryRow{
+		Id:           strconv.Itoa(len(card.History) + 1),
+		Date:         f.config.Now(),
+		Load:         load,
+		BusinessName: businessName,
+		Amount:       amount,
+	})
+}
+
+func (f *FakeHever) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session := f.session(w, r)
+
+	switch {
+	case r.URL.Path == "/signin.aspx" && r.Method == http.MethodGet:
+		f.handleLoginPage(w, session)
+
+	case r.URL.Path == "/signin.aspx" && r.Method == http.MethodPost:
+		f.handleLogin(w, r, session)
+
+	case r.URL.Path == "/acmplt.asmx/logo":
+		if r.URL.Query().Get("t") == session.pixelToken {
+			session.pixelVerified = true
+		}
+
+		w.Header().Set("Content-Type", "image/gif")
+
+	case r.URL.Path == "/site/logout":
+		session.authenticated = false
+		http.Redirect(w, r, "/logout.aspx", http.StatusFound)
+
+	case r.URL.Path == "/logout.aspx":
+		fmt.Fprint(w, `<html><body>להתראות</body></html>`)
+
+	case strings.HasPrefix(r.URL.Path, "/orders/"):
+		if !session.authenticated {
+			http.Redirect(w, r, "/logout.aspx", http.StatusFound)
+			return
+		}
+
+		f.handleCard(w, r, session)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Returns the session of the request, starting a new one if needed
+func (f *FakeHever) session(w http.ResponseWriter, r *http.Request) *fakeSession {
+	if cookie, err := r.Cookie(fakeSessionCookie); err == nil {
+		if session, ok := f.sessions[cookie.Value]; ok {
+			return session
+		}
+	}
+
+	id := randomHex(12)
+	session := &fakeSession{
+		pixelToken: strconv.FormatInt(f.config.Now().UnixMilli(), 10),
+	}
+
+	f.sessions[id] = session
+	http.SetCookie(w, &http.Cookie{Name: fakeSessionCookie, Value: id, Path: "/", HttpOnly: true})
+
+	return session
+}
+
+func (f *FakeHever) handleLoginPage(w http.ResponseWriter, session *fakeSession) {
+	fmt.Fprintf(w, `<html><body>
+<form id="signinForm" method="post">
+	<input type="hidden" name="bs" value="1">
+	<input type="hidden" name="tmpl_filename" value="signin_hvr">
+	<input type="text" name="tz" value="">
+	<input type="password" name="password" value="">
+</form>
+<img src="acmplt.asmx/logo?t=%s">
+</body></html>`, session.pixelToken)
+}
+
+func (f *FakeHever) handleLogin(w http.ResponseWriter, r *http.Request, session *fakeSession) {
+	r.ParseForm()
+
+	if !session.pixelVerified ||
+		r.PostForm.Get("oMode") != "login" ||
+		r.PostForm.Get("tz") != f.config.Username ||
+		r.PostForm.Get("password") != f.config.Password {
+
+		fmt.Fprintf(w, `<html><body>
+<form id="signinForm" method="post">
+	<input type="text" name="tz" value="%s">
+	<div id="msg3">פרטי ההזדהות שגויים</div>
+</form>
+</body></html>`, html.EscapeString(r.PostForm.Get("tz")))
+		return
+	}
+
+	session.authenticated = true
+	f.logins++
+
+	fmt.Fprint(w, `<html><body><div id="welcome">שלום</div></body></html>`)
+}
+
+// Returns the name of the card an orders page is for
+func fakeCardName(r *http.Request) string {
+	switch r.URL.Path {
+	case "/orders/gift_2000.aspx":
+		if r.URL.Query().Get("food") == "1" {
+			return FakeCardTeamim
+		}
+
+		return FakeCardKeva
+	}
+
+	return ""
+}
+
+func (f *FakeHever) handleCard(w http.ResponseWriter, r *http.Request, session *fakeSession) {
+	name := fakeCardName(r)
+
+	card, ok := f.config.Cards[name]
+	if !ok {
+		// The site redirects away from cards the account doesn't have
+		http.Redirect(w, r, "/default.aspx", http.StatusFound)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		f.handleCardPage(w, card)
+		return
+	}
+
+	r.ParseForm()
+
+	switch {
+	case r.PostForm.Get("balance_only") == "1":
+		fmt.Fprintf(w, "%s | %s | %s",
+			formatAmount(card.Balance),
+			formatAmount(card.MaxMonthlyAmount-card.MonthlyLoaded),
+			formatAmount(card.MaxOnCardAmount-card.Balance))
+
+	case r.PostForm.Get("om") == "load":
+		f.handleLoad(w, r, card)
+
+	case r.PostForm.Get("om") == "block" || r.PostForm.Get("om") == "unblock" || r.PostForm.Get("om") == "replace":
+		f.handleManage(w, r, session, name, card)
+
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+	}
+}
+
+func (f *FakeHever) handleCardPage(w http.ResponseWriter, card *FakeCard) {
+	var page strings.Builder
+
+	page.WriteString("<html><body>\n<script>\n")
+	for i, factor := range card.Factors {
+		fmt.Fprintf(&page, "\tvar gift_card_factor%d = %v;\n", i+1, factor.Factor)
+		fmt.Fprintf(&page, "\tvar gift_card_factor%d_price = %v;\n", i+1, factor.Amount)
+	}
+	fmt.Fprintf(&page, "\tvar max_month_load = %v;\n", card.MaxMonthlyAmount)
+	fmt.Fprintf(&page, "\tvar max_on_card = %v;\n", card.MaxOnCardAmount)
+	page.WriteString("</script>\n")
+
+	fmt.Fprintf(&page, `<form method="post"><input type="hidden" name="sn" value="%s"></form>`+"\n", card.SerialNumber)
+
+	page.WriteString("<table>\n")
+	for _, row := range card.History {
+		action := "רכישה"
+		if row.Load {
+			action = "טעינה"
+		}
+
+		fmt.Fprintf(&page, `<tr class="historyRows" id="%s"><td>%s</td><td>%s</td><td>%s</td><td>%v</td></tr>`+"\n",
+			row.Id, row.Date.Format("02/01/2006"), action, html.EscapeString(row.BusinessName), row.Amount)
+	}
+	page.WriteString("</table>\n</body></html>")
+
+	fmt.Fprint(w, page.String())
+}
+
+func (f *FakeHever) handleLoad(w http.ResponseWriter, r *http.Request, card *FakeCard) {
+	amount, err := strconv.ParseFloat(r.PostForm.Get("price"), 64)
+
+	switch {
+	case err != nil || amount < fakeMinimumLoad:
+		writeLoadFailure(w, "סכום הטעינה אינו תקין")
+		return
+	case r.PostForm.Get("sn") != card.SerialNumber:
+		writeLoadFailure(w, "הכרטיס לא נמצא")
+		return
+	case card.Blocked:
+		writeLoadFailure(w, "הכרטיס חסום")
+		return
+	case amount > card.MaxMonthlyAmount-card.MonthlyLoaded:
+		writeLoadFailure(w, "חריגה ממגבלת הטעינה החודשית")
+		return
+	case amount+card.Balance > card.MaxOnCardAmount:
+		writeLoadFailure(w, "חריגה ממגבלת הסכום בכרטיס")
+		return
+	}
+
+	for _, declined := range f.config.DeclinedCreditCards {
+		if r.PostForm.Get("card_num") == declined {
+			writeLoadFailure(w, "כרטיס האשראי נדחה")
+			return
+		}
+	}
+
+	card.Balance += amount
+	card.MonthlyLoaded += amount
+	f.appendHistory(card, true, "טעינת כרטיס", amount)
+
+	f.loadNumbers++
+
+	fmt.Fprintf(w, `<html><body>
+<div id="msg_ok">בקשת טעינת הכרטיס בוצעה. מספר ההזמנה: %d</div>
+<script>if ( 2 == 1 ) { show_msg(); }</script>
+</body></html>`, f.loadNumbers)
+}
+
+func (f *FakeHever) handleManage(w http.ResponseWriter, r *http.Request, session *fakeSession, name string, card *FakeCard) {
+	action := r.PostForm.Get("om")
+
+	// Asking for the action, which should be confirmed using the token
+	if r.PostForm.Get("confirm") != "1" {
+		switch {
+		case action == "block" && card.Blocked:
+			writeLoadError(w, "הכרטיס כבר חסום")
+			return
+		case action == "unblock" && !card.Blocked:
+			writeLoadError(w, "הכרטיס אינו חסום")
+			return
+		}
+
+		token := randomHex(8)
+		if session.confirmations == nil {
+			session.confirmations = make(map[string]fakeConfirmation)
+		}
+
+		session.confirmations[token] = fakeConfirmation{card: name, action: action}
+
+		fmt.Fprintf(w, `<html><body>
+<div id="confirm_msg">נא לאשר את הפעולה</div>
+<form method="post"><input type="hidden" name="confirm_token" value="%s"></form>
+</body></html>`, token)
+		return
+	}
+
+	// Tokens are used once, and only for the action they were given for
+	token := r.PostForm.Get("confirm_token")
+	confirmation, ok := session.confirmations[token]
+	delete(session.confirmations, token)
+
+	if !ok || confirmation != (fakeConfirmation{card: name, action: action}) {
+		writeLoadError(w, "פג תוקף האישור")
+		return
+	}
+
+	switch action {
+	case "block":
+		card.Blocked = true
+	case "unblock":
+		card.Blocked = false
+	case "replace":
+		card.Blocked = true
+		card.Replacements++
+	}
+
+	f.loadNumbers++
+
+	fmt.Fprintf(w, `<html><body>
+<div id="msg_ok">הבקשה התקבלה. מספר פנייה: %d</div>
+</body></html>`, f.loadNumbers)
+}
+
+func writeLoadError(w http.ResponseWriter, message string) {
+	fmt.Fprintf(w, `<html><body>
+<table class="table" bgcolor="red"><tr><td>%s</td></tr></table>
+</body></html>`, message)
+}
+
+// Writes the page of a failed load, which also has the status code of the load
+func writeLoadFailure(w http.ResponseWriter, message string) {
+	fmt.Fprintf(w, `<html><body>
+<table class="table" bgcolor="red"><tr><td>%s</td></tr></table>
+<script>if ( 1 == 1 ) { show_msg(); }</script>
+</body></html>`, message)
+}
+
+// Formats an amount the way the site does, e.g. 3,988
+func formatAmount(amount float64) string {
+	formatted := strconv.FormatFloat(amount, 'f', -1, 64)
+
+	integer, fraction, _ := strings.Cut(formatted, ".")
+	for i := len(integer) - 3; i > 0 && integer[i-1] != '-'; i -= 3 {
+		integer = integer[:i] + "," + integer[i:]
+	}
+
+	if fraction != "" {
+		return integer + "." + fraction
+	}
+
+	return integer
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
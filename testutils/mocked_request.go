@@ -62,6 +62,12 @@ func (m *MockedRequest) File(fileName string) *MockedRequest {
 	return m
 }
 
+// Set the body of the MockedRequest to a given string
+func (m *MockedRequest) Body(body string) *MockedRequest {
+	m.response.Body = []byte(body)
+	return m
+}
+
 func (m *MockedRequest) Once() *MockedRequest {
 	return m.Times(1)
 }